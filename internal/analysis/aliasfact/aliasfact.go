@@ -18,35 +18,121 @@
 // Package aliasfact provides a fact that resolves the type of alias target
 // fields. It uses the ECS definition fact to ensure that any external ECS
 // definitions are resolved. The pkgspec.Field.Type is overwritten with the
-// type of the target field. A diagnostic is reported if the target field does
-// not exist in the same directory, and the unresolved alias field is included
-// in the fact.
+// type of the target field. Targets are looked up by name among all fields
+// files of the data stream containing the alias, and chains of aliases are
+// followed. A diagnostic is reported if the target is missing, if the alias
+// is part of a cycle, or if the target is another alias or an object. The
+// unresolved alias field is included in the fact.
 package aliasfact
 
 import (
 	"fmt"
 	"path/filepath"
+	"reflect"
+	"strings"
 
 	"github.com/andrewkroh/go-package-spec/pkgspec"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
 	"github.com/andrewkroh/fydler/internal/analysis/ecsdefinitionfact"
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
 )
 
 var Analyzer = &analysis.Analyzer{
 	Name: "aliasfact",
 	Description: "Gathers the field type of the target field of an alias. " +
 		"It reports a diagnostic if the target field does not resolve to a static field.",
-	Run:      run,
-	Requires: []*analysis.Analyzer{ecsdefinitionfact.Analyzer},
+	Run:        run,
+	Requires:   []*analysis.Analyzer{ecsdefinitionfact.Analyzer},
+	Reads:      []reflect.Type{reflect.TypeFor[*ecsdefinitionfact.Fact]()},
+	ResultType: reflect.TypeFor[*Fact](),
 }
 
 type Fact struct {
 	ResolvedAliases []*pkgspec.Field // Field data where the type is overwritten with the type of the target field.
 }
 
+// index maps owner directories (see pkgmodel.Owner.Dir) to the fields declared
+// by the owner, keyed by name.
+type index struct {
+	packages *pkgmodel.Model
+	byDir    map[string]map[string]*pkgspec.Field
+}
+
+func newIndex(packages *pkgmodel.Model, fields []*pkgspec.Field) *index {
+	idx := &index{packages: packages, byDir: map[string]map[string]*pkgspec.Field{}}
+	for _, f := range fields {
+		dir := idx.ownerDir(f)
+		byName, found := idx.byDir[dir]
+		if !found {
+			byName = map[string]*pkgspec.Field{}
+			idx.byDir[dir] = byName
+		}
+		// Keep the first declaration so that results are deterministic.
+		if _, found := byName[f.Name]; !found {
+			byName[f.Name] = f
+		}
+	}
+	return idx
+}
+
+// lookup returns the field with the given name that is declared in the same
+// data stream as the from field.
+func (idx *index) lookup(from *pkgspec.Field, name string) *pkgspec.Field {
+	return idx.byDir[idx.ownerDir(from)][name]
+}
+
+// ownerDir returns the directory of the data stream, transform, or package
+// that declares the field. Fields files that are not in a package are grouped
+// by their directory.
+func (idx *index) ownerDir(f *pkgspec.Field) string {
+	if owner, found := idx.packages.Owner(f.FilePath()); found {
+		return owner.Dir()
+	}
+	return filepath.Dir(f.FilePath())
+}
+
+// resolution is the outcome of following an alias to its final target.
+type resolution struct {
+	chain   []string       // Names of the fields visited, starting with the alias.
+	target  *pkgspec.Field // Final target. Nil if missing or a cycle was found.
+	missing string         // Name of the missing target.
+	cycle   bool           // The alias chain loops back on itself.
+}
+
+// resolve follows the alias chain starting at f.
+func (idx *index) resolve(f *pkgspec.Field) resolution {
+	r := resolution{chain: []string{f.Name}}
+	seen := map[string]struct{}{f.Name: {}}
+
+	for cur := f; ; {
+		next := idx.lookup(cur, cur.Path)
+		if next == nil {
+			r.missing = cur.Path
+			return r
+		}
+		r.chain = append(r.chain, next.Name)
+		if _, found := seen[next.Name]; found {
+			r.cycle = true
+			return r
+		}
+		seen[next.Name] = struct{}{}
+
+		if next.Type != "alias" {
+			r.target = next
+			return r
+		}
+		cur = next
+	}
+}
+
 func run(pass *analysis.Pass) (interface{}, error) {
-	ecsDefinitionFact := pass.ResultOf[ecsdefinitionfact.Analyzer].(*ecsdefinitionfact.Fact)
+	ecsDefinitionFact, err := analysis.Result[*ecsdefinitionfact.Fact](pass, ecsdefinitionfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := newIndex(pass.Packages, ecsDefinitionFact.EnrichedFlat)
 	fact := &Fact{ResolvedAliases: make([]*pkgspec.Field, 0, len(ecsDefinitionFact.EnrichedFlat))}
 
 	for _, f := range ecsDefinitionFact.EnrichedFlat {
@@ -54,29 +140,37 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			fact.ResolvedAliases = append(fact.ResolvedAliases, f)
 			continue
 		}
-		dir := filepath.Dir(f.FilePath())
 
-		var resolvedType pkgspec.FieldType
-		for _, aliased := range ecsDefinitionFact.EnrichedFlat {
-			if f.Path == aliased.Name {
-				aliasedDir := filepath.Dir(aliased.FilePath())
-				if dir != aliasedDir {
-					continue
-				}
-
-				resolvedType = aliased.Type
-
-				break
-			}
+		r := idx.resolve(f)
+
+		var message string
+		switch {
+		case len(r.chain) == 1:
+			message = fmt.Sprintf("%s is declared as an alias, but the aliased field %s does not exist in the same data stream", f.Name, f.Path)
+		case r.cycle && r.chain[len(r.chain)-1] == f.Name:
+			message = fmt.Sprintf("%s is declared as an alias, but the alias path forms a cycle (%s)", f.Name, strings.Join(r.chain, " -> "))
+		case r.missing != "":
+			message = fmt.Sprintf("%s is declared as an alias, but the alias path ends at %s, which does not exist in the same data stream (%s)",
+				f.Name, r.missing, strings.Join(append(r.chain, r.missing), " -> "))
+		case len(r.chain) > 2:
+			message = fmt.Sprintf("%s is declared as an alias to %s, but that field is also an alias; an alias must target a concrete field", f.Name, f.Path)
+		case isObjectType(r.target.Type):
+			message = fmt.Sprintf("%s is declared as an alias to %s, but that field has type %s and an alias cannot target an object", f.Name, f.Path, r.target.Type)
 		}
-
-		if resolvedType == "" {
+		if message != "" {
+			pos, end := analysis.KeyRange(pass, f, "path")
 			pass.Report(analysis.Diagnostic{
-				Pos:      analysis.NewPos(f.FileMetadata),
+				Pos:      pos,
+				End:      end,
 				Category: pass.Analyzer.Name,
-				Message:  fmt.Sprintf("%s is declared as an alias, but the aliased field %s does not exist in the same directory", f.Name, f.Path),
+				Field:    f.Name,
+				Message:  message,
 			})
+		}
 
+		// An alias to another alias is still resolved to the type at the end
+		// of the chain so that downstream analyzers see the effective type.
+		if r.target == nil || isObjectType(r.target.Type) {
 			// Put the unresolved alias into the list so that it can be considered by downstream analyzers.
 			fact.ResolvedAliases = append(fact.ResolvedAliases, f)
 			continue
@@ -87,10 +181,20 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			tmp := *f
 			f = &tmp
 		}
-		f.Type = resolvedType
+		f.Type = r.target.Type
 
 		fact.ResolvedAliases = append(fact.ResolvedAliases, f)
 	}
 
 	return fact, nil
 }
+
+// isObjectType returns true for field types that cannot be the target of an
+// alias.
+func isObjectType(typ pkgspec.FieldType) bool {
+	switch typ {
+	case "object", "nested", "group":
+		return true
+	}
+	return false
+}
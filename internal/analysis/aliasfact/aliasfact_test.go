@@ -50,11 +50,103 @@ func Test(t *testing.T) {
 				{
 					Pos: analysis.Pos{
 						File: "testdata/my_package/data_stream/unresolved_alias/fields/unresolved_alias.yml",
-						Line: 2,
+						Line: 4,
 						Col:  3,
 					},
+					End: analysis.Pos{
+						File: "testdata/my_package/data_stream/unresolved_alias/fields/unresolved_alias.yml",
+						Line: 4,
+						Col:  16,
+					},
+					Category: "aliasfact",
+					Field:    "body",
+					Message:  "body is declared as an alias, but the aliased field message does not exist in the same data stream",
+				},
+			},
+		},
+		{
+			Path: "testdata/my_package/data_stream/cross_file/fields/*.yml",
+			Fields: map[string]string{
+				"body":    "match_only_text",
+				"message": "match_only_text",
+			},
+		},
+		{
+			Path: "testdata/my_package/data_stream/chain/fields/chain.yml",
+			Fields: map[string]string{
+				"first":   "match_only_text",
+				"second":  "match_only_text",
+				"message": "match_only_text",
+			},
+			Diags: []analysis.Diagnostic{
+				{
+					Pos:      analysis.Pos{File: "testdata/my_package/data_stream/chain/fields/chain.yml", Line: 4, Col: 3},
+					End:      analysis.Pos{File: "testdata/my_package/data_stream/chain/fields/chain.yml", Line: 4, Col: 15},
+					Category: "aliasfact",
+					Field:    "first",
+					Message:  "first is declared as an alias to second, but that field is also an alias; an alias must target a concrete field",
+				},
+			},
+		},
+		{
+			Path: "testdata/my_package/data_stream/cycle/fields/cycle.yml",
+			Fields: map[string]string{
+				"first":  "alias",
+				"second": "alias",
+			},
+			Diags: []analysis.Diagnostic{
+				{
+					Pos:      analysis.Pos{File: "testdata/my_package/data_stream/cycle/fields/cycle.yml", Line: 4, Col: 3},
+					End:      analysis.Pos{File: "testdata/my_package/data_stream/cycle/fields/cycle.yml", Line: 4, Col: 15},
+					Category: "aliasfact",
+					Field:    "first",
+					Message:  "first is declared as an alias, but the alias path forms a cycle (first -> second -> first)",
+				},
+				{
+					Pos:      analysis.Pos{File: "testdata/my_package/data_stream/cycle/fields/cycle.yml", Line: 7, Col: 3},
+					End:      analysis.Pos{File: "testdata/my_package/data_stream/cycle/fields/cycle.yml", Line: 7, Col: 14},
+					Category: "aliasfact",
+					Field:    "second",
+					Message:  "second is declared as an alias, but the alias path forms a cycle (second -> first -> second)",
+				},
+			},
+		},
+		{
+			Path: "testdata/my_package/data_stream/missing_target/fields/missing_target.yml",
+			Fields: map[string]string{
+				"first":  "alias",
+				"second": "alias",
+			},
+			Diags: []analysis.Diagnostic{
+				{
+					Pos:      analysis.Pos{File: "testdata/my_package/data_stream/missing_target/fields/missing_target.yml", Line: 4, Col: 3},
+					End:      analysis.Pos{File: "testdata/my_package/data_stream/missing_target/fields/missing_target.yml", Line: 4, Col: 15},
+					Category: "aliasfact",
+					Field:    "first",
+					Message:  "first is declared as an alias, but the alias path ends at missing, which does not exist in the same data stream (first -> second -> missing)",
+				},
+				{
+					Pos:      analysis.Pos{File: "testdata/my_package/data_stream/missing_target/fields/missing_target.yml", Line: 7, Col: 3},
+					End:      analysis.Pos{File: "testdata/my_package/data_stream/missing_target/fields/missing_target.yml", Line: 7, Col: 16},
+					Category: "aliasfact",
+					Field:    "second",
+					Message:  "second is declared as an alias, but the aliased field missing does not exist in the same data stream",
+				},
+			},
+		},
+		{
+			Path: "testdata/my_package/data_stream/object/fields/object.yml",
+			Fields: map[string]string{
+				"body":   "alias",
+				"labels": "object",
+			},
+			Diags: []analysis.Diagnostic{
+				{
+					Pos:      analysis.Pos{File: "testdata/my_package/data_stream/object/fields/object.yml", Line: 4, Col: 3},
+					End:      analysis.Pos{File: "testdata/my_package/data_stream/object/fields/object.yml", Line: 4, Col: 15},
 					Category: "aliasfact",
-					Message:  "body is declared as an alias, but the aliased field message does not exist in the same directory",
+					Field:    "body",
+					Message:  "body is declared as an alias to labels, but that field has type object and an alias cannot target an object",
 				},
 			},
 		},
@@ -63,7 +155,7 @@ func Test(t *testing.T) {
 	for _, tc := range testCases {
 		tc := tc
 
-		t.Run(filepath.Base(tc.Path), func(t *testing.T) {
+		t.Run(filepath.Base(filepath.Dir(filepath.Dir(tc.Path))), func(t *testing.T) {
 			results, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer}, tc.Path)
 			if err != nil {
 				t.Fatal(err)
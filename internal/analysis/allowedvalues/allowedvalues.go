@@ -0,0 +1,290 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package allowedvalues provides an analyzer that checks the values of the
+// ECS categorization fields (event.kind, event.category, event.type, and
+// event.outcome) against the allowed values of the data stream's ECS version
+// (see ecsindexfact.Table). Sample events and pipeline test outputs are also
+// checked against the event.category and event.type compatibility matrix of
+// that version. Ingest pipelines are only checked for allowed values because
+// the combination of values in a document depends on the pipeline's
+// conditions.
+package allowedvalues
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"gopkg.in/yaml.v3"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsversionfact"
+	"github.com/andrewkroh/fydler/internal/jsonwalk"
+	"github.com/andrewkroh/fydler/internal/pipelinewalk"
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "allowedvalues",
+	Description: "Detects values of event.kind, event.category, event.type, and event.outcome that are not " +
+		"allowed by ECS, or event.category and event.type combinations that ECS does not expect.",
+	Requires: []*analysis.Analyzer{ecsversionfact.Analyzer, ecsindexfact.Analyzer},
+	Reads:    []reflect.Type{reflect.TypeFor[*ecsversionfact.Fact](), reflect.TypeFor[*ecsindexfact.Fact]()},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ecsVersionsFact, err := analysis.Result[*ecsversionfact.Fact](pass, ecsversionfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+	ecsIndex, err := analysis.Result[*ecsindexfact.Fact](pass, ecsindexfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, pass.Packages.ForEachOwner(pass.Context, pass.Flat, func(owner pkgmodel.Owner, fields []*pkgspec.Field) error {
+		if owner.Transform != nil {
+			return nil
+		}
+
+		// The ECS version is only known for fields files that have
+		// 'external: ecs' fields.
+		var version string
+		for _, f := range fields {
+			if version = ecsVersionsFact.ECSVersion(f.FilePath()); version != "" {
+				break
+			}
+		}
+
+		table, err := ecsIndex.Table(version)
+		if err != nil {
+			// Fall back to the latest ECS version. The ecsversionfact
+			// reports the problem with the version.
+			if table, err = ecsIndex.Latest(); err != nil {
+				return fmt.Errorf("failed to load ECS fields: %w", err)
+			}
+			version = ""
+		}
+		if version == "" {
+			version = ecsIndex.LatestVersion()
+		}
+
+		c := &checker{
+			pass:    pass,
+			table:   table,
+			version: strings.TrimPrefix(version, "v"),
+		}
+		c.checkSampleEvent(owner.Dir())
+		c.checkPipelineTests(owner.Dir())
+		c.checkIngestPipelines(owner.Dir())
+		return nil
+	})
+}
+
+// checker checks the documents and ingest pipelines of a data stream.
+type checker struct {
+	pass    *analysis.Pass
+	table   *ecsindexfact.Table
+	version string // ECS version for messages.
+}
+
+func (c *checker) checkSampleEvent(dir string) {
+	path := jsonwalk.SampleEventFile(dir)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	d := c.newDocumentChecker(path)
+	jsonwalk.Document(c.pass.Context, data, d.visit)
+	d.checkCategoryTypes()
+}
+
+func (c *checker) checkPipelineTests(dir string) {
+	for _, path := range jsonwalk.PipelineTestFiles(dir) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		d := c.newDocumentChecker(path)
+		jsonwalk.PipelineTestDocuments(c.pass.Context, data, d.visit, d.checkCategoryTypes)
+	}
+}
+
+// documentChecker checks the documents of a single file. Each problem is
+// reported once per file.
+type documentChecker struct {
+	*checker
+	file     string
+	reported map[string]bool
+
+	// Values of event.category and event.type in the current document.
+	categories []string
+	types      []string
+	typesSpan  jsonwalk.Span
+}
+
+func (c *checker) newDocumentChecker(file string) *documentChecker {
+	return &documentChecker{checker: c, file: file, reported: map[string]bool{}}
+}
+
+// visit is a jsonwalk.Visitor that checks values against the allowed values.
+func (d *documentChecker) visit(fieldPath string, kind jsonwalk.Kind, value any, span jsonwalk.Span) {
+	allowed, found := d.table.AllowedValues[fieldPath]
+	if !found {
+		return
+	}
+
+	var values []string
+	switch kind {
+	case jsonwalk.Scalar:
+		if s, ok := value.(string); ok {
+			values = append(values, s)
+		}
+	case jsonwalk.Array:
+		for _, v := range value.([]any) {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	switch fieldPath {
+	case "event.category":
+		d.categories = values
+	case "event.type":
+		d.types, d.typesSpan = values, span
+	}
+
+	for _, v := range values {
+		if slices.Contains(allowed, v) {
+			continue
+		}
+		key := fieldPath + "=" + v
+		if d.reported[key] {
+			continue
+		}
+		d.reported[key] = true
+
+		d.pass.Report(analysis.Diagnostic{
+			Pos:      analysis.Pos{File: d.file, Line: span.Line, Col: span.Col},
+			End:      analysis.Pos{File: d.file, Line: span.Line, Col: span.EndCol},
+			Category: d.pass.Analyzer.Name,
+			Field:    fieldPath,
+			Message:  d.notAllowedMessage(fieldPath, v),
+		})
+	}
+}
+
+// checkCategoryTypes reports event.type values that ECS does not expect with
+// any of the document's event.category values. It is called at the end of
+// each document.
+func (d *documentChecker) checkCategoryTypes() {
+	categories, types, span := d.categories, d.types, d.typesSpan
+	d.categories, d.types = nil, nil
+
+	// Only categories that are allowed have expected types. Invalid
+	// categories have already been reported.
+	var expected []string
+	var checked []string
+	for _, category := range categories {
+		if e, found := d.table.ExpectedEventTypes[category]; found {
+			expected = append(expected, e...)
+			checked = append(checked, strconv.Quote(category))
+		}
+	}
+	if len(checked) == 0 {
+		return
+	}
+	slices.Sort(expected)
+	expected = slices.Compact(expected)
+
+	for _, typ := range types {
+		if slices.Contains(expected, typ) || !slices.Contains(d.table.AllowedValues["event.type"], typ) {
+			continue
+		}
+		key := strings.Join(checked, ",") + ":" + typ
+		if d.reported[key] {
+			continue
+		}
+		d.reported[key] = true
+
+		d.pass.Report(analysis.Diagnostic{
+			Pos:      analysis.Pos{File: d.file, Line: span.Line, Col: span.Col},
+			End:      analysis.Pos{File: d.file, Line: span.Line, Col: span.EndCol},
+			Category: d.pass.Analyzer.Name,
+			Field:    "event.type",
+			Message: fmt.Sprintf("event.type %q is not expected with event.category %s in ECS %s; the expected types are %s",
+				typ, strings.Join(checked, ", "), d.version, strings.Join(expected, ", ")),
+		})
+	}
+}
+
+// checkIngestPipelines checks the literal values written by set and append
+// processors.
+func (c *checker) checkIngestPipelines(dir string) {
+	for _, path := range pipelinewalk.Files(dir) {
+		// Pipelines that cannot be parsed are not checked.
+		_ = pipelinewalk.File(path, func(typ, config *yaml.Node) {
+			if typ.Value != "set" && typ.Value != "append" {
+				return
+			}
+			field := pipelinewalk.MappingValue(config, "field")
+			if field == nil {
+				return
+			}
+			allowed, found := c.table.AllowedValues[field.Value]
+			if !found {
+				return
+			}
+
+			value := pipelinewalk.MappingValue(config, "value")
+			if value == nil {
+				return
+			}
+			values := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				values = value.Content
+			}
+			for _, v := range values {
+				if v.Kind != yaml.ScalarNode || v.Tag != "!!str" || strings.Contains(v.Value, "{{") || slices.Contains(allowed, v.Value) {
+					continue
+				}
+				pos, end := analysis.NodeRange(path, v)
+				c.pass.Report(analysis.Diagnostic{
+					Pos:      pos,
+					End:      end,
+					Category: c.pass.Analyzer.Name,
+					Field:    field.Value,
+					Message:  c.notAllowedMessage(field.Value, v.Value),
+				})
+			}
+		})
+	}
+}
+
+func (c *checker) notAllowedMessage(field, value string) string {
+	return fmt.Sprintf("%q is not an allowed value of %s in ECS %s", value, field, c.version)
+}
@@ -0,0 +1,97 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package allowedvalues
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
+	"github.com/andrewkroh/fydler/internal/fydler"
+)
+
+func Test(t *testing.T) {
+	const foo = "testdata/my_package/data_stream/foo"
+	sampleEvent := filepath.Join(foo, "sample_event.json")
+	pipelineTest := filepath.Join(foo, "_dev/test/pipeline", "test-sample.log-expected.json")
+	pipeline := filepath.Join(foo, "elasticsearch/ingest_pipeline", "default.yml")
+
+	// The package references ECS 99.0.0, which is defined by the schema
+	// fixture rather than by the versions embedded in go-ecs.
+	require.NoError(t, ecsindexfact.Analyzer.Flags.Set("schema", "testdata/schemas"))
+	t.Cleanup(func() { _ = ecsindexfact.Analyzer.Flags.Set("schema", "") })
+
+	_, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer}, filepath.Join(foo, "fields/ecs.yml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []analysis.Diagnostic{
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 3, Col: 5},
+			End:      analysis.Pos{File: sampleEvent, Line: 3, Col: 15},
+			Category: "allowedvalues",
+			Field:    "event.category",
+			Message:  `"authentication_success" is not an allowed value of event.category in ECS 99.0.0`,
+		},
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 5, Col: 5},
+			End:      analysis.Pos{File: sampleEvent, Line: 5, Col: 14},
+			Category: "allowedvalues",
+			Field:    "event.outcome",
+			Message:  `"succeeded" is not an allowed value of event.outcome in ECS 99.0.0`,
+		},
+		{
+			Pos:      analysis.Pos{File: pipelineTest, Line: 12, Col: 9},
+			End:      analysis.Pos{File: pipelineTest, Line: 12, Col: 15},
+			Category: "allowedvalues",
+			Field:    "event.type",
+			Message:  `event.type "connection" is not expected with event.category "authentication" in ECS 99.0.0; the expected types are end, info, start`,
+		},
+		{
+			Pos:      analysis.Pos{File: pipelineTest, Line: 12, Col: 9},
+			End:      analysis.Pos{File: pipelineTest, Line: 12, Col: 15},
+			Category: "allowedvalues",
+			Field:    "event.type",
+			Message:  `event.type "creation" is not expected with event.category "authentication" in ECS 99.0.0; the expected types are end, info, start`,
+		},
+		{
+			Pos:      analysis.Pos{File: pipeline, Line: 6, Col: 14},
+			End:      analysis.Pos{File: pipeline, Line: 6, Col: 20},
+			Category: "allowedvalues",
+			Field:    "event.kind",
+			Message:  `"events" is not an allowed value of event.kind in ECS 99.0.0`,
+		},
+		{
+			Pos:      analysis.Pos{File: pipeline, Line: 9, Col: 24},
+			End:      analysis.Pos{File: pipeline, Line: 9, Col: 34},
+			Category: "allowedvalues",
+			Field:    "event.category",
+			Message:  `"networking" is not an allowed value of event.category in ECS 99.0.0`,
+		},
+		{
+			Pos:      analysis.Pos{File: pipeline, Line: 18, Col: 18},
+			End:      analysis.Pos{File: pipeline, Line: 18, Col: 25},
+			Category: "allowedvalues",
+			Field:    "event.type",
+			Message:  `"connect" is not an allowed value of event.type in ECS 99.0.0`,
+		},
+	}, diags)
+}
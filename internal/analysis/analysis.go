@@ -20,17 +20,25 @@
 package analysis
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/andrewkroh/go-package-spec/pkgspec"
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
 	"github.com/andrewkroh/fydler/internal/yamledit"
 )
 
@@ -40,6 +48,16 @@ type Analyzer struct {
 	CanFix      bool
 	Requires    []*Analyzer
 
+	// ResultType is the type of the result (fact) returned by Run. It must be
+	// set by analyzers that are listed in the Requires of other analyzers.
+	// Use Result to read it from a dependent analyzer.
+	ResultType reflect.Type
+
+	// Reads lists the types of the facts that Run reads with Result. Each
+	// type must be the ResultType of an analyzer in Requires so that a
+	// missing requirement is detected before any analyzer runs.
+	Reads []reflect.Type
+
 	Flags flag.FlagSet
 
 	Run func(*Pass) (interface{}, error)
@@ -48,16 +66,31 @@ type Analyzer struct {
 type Pass struct {
 	Analyzer *Analyzer
 
+	// Context is canceled when the analysis should stop. Analyzers that do
+	// significant work should check it periodically and return its error.
+	// It is never nil.
+	Context context.Context
+
 	Fix bool // Should the analyzer apply fixes to AST?
 
+	// Suggest indicates that analyzers should attach suggested fixes to the
+	// diagnostics that they could have fixed automatically.
+	Suggest bool
+
 	// Field information.
 	Fields []*pkgspec.Field // Fields from every file.
 	Flat   []*pkgspec.Field // Flat view of all fields sorted by file and line number.
 
+	// Packages describes the packages containing the fields files, including
+	// their manifests, data streams, and transforms. It is never nil. Fields
+	// files that are not inside a package have no owner in the model.
+	Packages *pkgmodel.Model
+
 	// Map of file paths to the AST of that file. This is available when Fix is true.
 	// Analyzers may add, modify, and delete map attributes, but they should not
 	// add or remove entire field list entries (any operation that changes indices
-	// in YAML paths would break other analyzers).
+	// in YAML paths would break other analyzers). Other YAML files, such as a
+	// package's build.yml, are added to the map by LoadAST.
 	AST map[string]*AST
 
 	// ResultOf provides the inputs to this analysis pass, which are
@@ -68,6 +101,10 @@ type Pass struct {
 	ResultOf map[*Analyzer]interface{}
 
 	Report func(Diagnostic)
+
+	sources map[string]string    // Cache of file contents.
+	files   map[string]*ast.File // Cache of unmodified file ASTs used by KeyRange.
+	loaded  map[string]struct{}  // Cleaned paths of the loaded fields files.
 }
 
 type Pos struct {
@@ -95,11 +132,50 @@ func (p Pos) MarshalJSON() ([]byte, error) {
 	return json.Marshal(p.String())
 }
 
+// UnmarshalJSON parses a position in the format produced by MarshalJSON
+// (file:line or file:line:col).
+func (p *Pos) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	// Parse from the right because the file path may contain colons.
+	var nums []int
+	for len(nums) < 2 {
+		idx := strings.LastIndexByte(s, ':')
+		if idx == -1 {
+			break
+		}
+		n, err := strconv.Atoi(s[idx+1:])
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+		s = s[:idx]
+	}
+
+	*p = Pos{File: s}
+	switch len(nums) {
+	case 2:
+		p.Line, p.Col = nums[1], nums[0]
+	case 1:
+		p.Line = nums[0]
+	default:
+		return fmt.Errorf("invalid position %q", s)
+	}
+	return nil
+}
+
 type Diagnostic struct {
-	Pos      Pos
-	Category string
-	Message  string
-	Related  []RelatedInformation `json:"Related,omitempty"`
+	Pos            Pos
+	End            Pos `json:"End,omitzero"` // Optional position after the last character of the range.
+	Category       string
+	Field          string `json:"Field,omitempty"` // Name of the field (or other key) that the diagnostic is about.
+	Message        string
+	Related        []RelatedInformation `json:"Related,omitempty"`
+	SuggestedFixes []SuggestedFix       `json:"SuggestedFixes,omitempty"`
+	Owners         []string             `json:"Owners,omitempty"` // Code owners of the file.
 }
 
 type RelatedInformation struct {
@@ -107,11 +183,68 @@ type RelatedInformation struct {
 	Message string
 }
 
+// SuggestedFix is a change that resolves a diagnostic. It replaces the lines
+// from Pos through End (inclusive) with NewText.
+type SuggestedFix struct {
+	Message string
+	Pos     Pos
+	End     Pos
+	NewText string
+}
+
 type AST struct {
 	File     *ast.File
 	Modified bool // Modified tracks whether File has been modified.
 }
 
+// LoadAST returns the AST of a YAML file that is not a fields file (e.g. a
+// package's build.yml) so that it can be fixed. The file is parsed once and
+// added to pass.AST, and it is written back like fields files when Modified
+// is set. It can only be used when Fix is true.
+func (p *Pass) LoadAST(path string) (*AST, error) {
+	if !p.Fix {
+		return nil, errors.New("ASTs are only available when fixing")
+	}
+	if a, found := p.AST[path]; found {
+		return a, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading AST for %s: %w", path, err)
+	}
+	f.Name = path
+
+	if p.AST == nil {
+		p.AST = map[string]*AST{}
+	}
+	a := &AST{File: f}
+	p.AST[path] = a
+	return a, nil
+}
+
+// Complete returns true if every fields file of the owner was loaded. Checks
+// that report undeclared fields need all of them because every field appears
+// undeclared when only some of the owner's fields files were loaded.
+func (p *Pass) Complete(owner pkgmodel.Owner) bool {
+	if p.loaded == nil {
+		p.loaded = make(map[string]struct{}, len(p.Fields))
+		for _, f := range p.Fields {
+			p.loaded[filepath.Clean(f.FilePath())] = struct{}{}
+		}
+	}
+	for _, path := range owner.FieldsFiles() {
+		if _, found := p.loaded[filepath.Clean(path)]; !found {
+			return false
+		}
+	}
+	return true
+}
+
 type Printer func(diags []Diagnostic, w io.Writer)
 
 // VisitFields can be used to iterate over non-flat fields. Use this when you
@@ -187,3 +320,99 @@ func YAMLPath(f *pkgspec.Field) string {
 	}
 	return b.String()
 }
+
+// Edit is a modification to the AST of a YAML file.
+type Edit func(*ast.File) error
+
+// SuggestEdit returns the fix that results from applying the edit to a private
+// copy of the AST of the field's file. pass.AST is not modified. It returns
+// nil if pass.Suggest is false, if the edit makes no changes, or if the file
+// cannot be reproduced from its AST such that the change can be expressed as
+// a replacement of lines in the original file.
+func SuggestEdit(pass *Pass, field *pkgspec.Field, message string, edit Edit) ([]SuggestedFix, error) {
+	return SuggestFileEdit(pass, field.FilePath(), message, edit)
+}
+
+// SuggestFileEdit is like SuggestEdit, but it applies the edit to the YAML file
+// at path rather than to the file containing a field.
+func SuggestFileEdit(pass *Pass, path, message string, edit Edit) ([]SuggestedFix, error) {
+	if !pass.Suggest {
+		return nil, nil
+	}
+
+	original, err := pass.source(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parser.ParseBytes([]byte(original), parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing %s: %w", path, err)
+	}
+	baseline := f.String()
+	if strings.TrimRight(baseline, "\n") != strings.TrimRight(original, "\n") {
+		return nil, nil
+	}
+
+	if err = edit(f); err != nil {
+		return nil, err
+	}
+
+	fix := diffLines(path, splitLines(baseline), splitLines(f.String()))
+	if fix == nil {
+		return nil, nil
+	}
+	fix.Message = message
+	return []SuggestedFix{*fix}, nil
+}
+
+// SuggestDeleteKey returns a suggested fix that deletes the specified key
+// from the field. See SuggestEdit.
+func SuggestDeleteKey(field *pkgspec.Field, key string, pass *Pass) ([]SuggestedFix, error) {
+	return SuggestEdit(pass, field, fmt.Sprintf("Remove %q", key), func(f *ast.File) error {
+		p, err := yaml.PathString(YAMLPath(field) + "." + key)
+		if err != nil {
+			return err
+		}
+		return yamledit.DeleteNode(f, p)
+	})
+}
+
+func splitLines(s string) []string {
+	return strings.Split(strings.TrimRight(s, "\n"), "\n")
+}
+
+// diffLines returns a fix that replaces the lines that differ between a and
+// b. Because a fix must replace at least one line, an insertion also replaces
+// the line preceding it.
+func diffLines(path string, a, b []string) *SuggestedFix {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	if prefix == len(a) && prefix == len(b) {
+		return nil
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	start, end := prefix, len(a)-suffix
+	newLines := slices.Clone(b[prefix : len(b)-suffix])
+	if start == end {
+		if start > 0 {
+			start--
+			newLines = append([]string{a[start]}, newLines...)
+		} else {
+			newLines = append(newLines, a[end])
+			end++
+		}
+	}
+
+	return &SuggestedFix{
+		Pos:     Pos{File: path, Line: start + 1},
+		End:     Pos{File: path, Line: end},
+		NewText: strings.Join(newLines, "\n"),
+	}
+}
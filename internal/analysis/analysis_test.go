@@ -0,0 +1,282 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package analysis
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
+)
+
+func TestPosJSON(t *testing.T) {
+	testCases := []Pos{
+		{File: "fields.yml", Line: 2, Col: 3},
+		{File: "sample_event.json", Line: 9},
+		{File: `C:\packages\foo\fields.yml`, Line: 1, Col: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.String(), func(t *testing.T) {
+			data, err := json.Marshal(tc)
+			require.NoError(t, err)
+
+			var p Pos
+			require.NoError(t, json.Unmarshal(data, &p))
+			assert.Equal(t, tc, p)
+		})
+	}
+
+	var p Pos
+	assert.Error(t, json.Unmarshal([]byte(`"fields.yml"`), &p))
+}
+
+func TestSuggestDeleteKey(t *testing.T) {
+	const fieldsYAML = `- name: foo
+  type: group
+  fields:
+    - name: bar
+      type: keyword
+      typo: true
+    - name: baz
+      type: long
+`
+	path := filepath.Join(t.TempDir(), "fields.yml")
+	require.NoError(t, os.WriteFile(path, []byte(fieldsYAML), 0o644))
+
+	var fields []pkgspec.Field
+	require.NoError(t, yaml.Unmarshal([]byte(fieldsYAML), &fields))
+	pkgspec.AnnotateFileMetadata(path, &fields)
+	pkgspec.AnnotateFieldPointers(fields)
+	bar := &fields[0].Fields[0]
+
+	pass := &Pass{}
+	fixes, err := SuggestDeleteKey(bar, "typo", pass)
+	require.NoError(t, err)
+	assert.Nil(t, fixes, "suggestions are disabled")
+
+	pass.Suggest = true
+	fixes, err = SuggestDeleteKey(bar, "typo", pass)
+	require.NoError(t, err)
+	require.Len(t, fixes, 1)
+	assert.Equal(t, SuggestedFix{
+		Message: `Remove "typo"`,
+		Pos:     Pos{File: path, Line: 6},
+		End:     Pos{File: path, Line: 6},
+		NewText: "",
+	}, fixes[0])
+}
+
+func TestDiffLines(t *testing.T) {
+	testCases := []struct {
+		name string
+		a, b []string
+		want *SuggestedFix
+	}{
+		{
+			name: "equal",
+			a:    []string{"a", "b"},
+			b:    []string{"a", "b"},
+		},
+		{
+			name: "replace",
+			a:    []string{"a", "b", "c"},
+			b:    []string{"a", "x", "c"},
+			want: &SuggestedFix{Pos: Pos{File: "f", Line: 2}, End: Pos{File: "f", Line: 2}, NewText: "x"},
+		},
+		{
+			name: "delete",
+			a:    []string{"a", "b", "c"},
+			b:    []string{"a", "c"},
+			want: &SuggestedFix{Pos: Pos{File: "f", Line: 2}, End: Pos{File: "f", Line: 2}},
+		},
+		{
+			name: "insert",
+			a:    []string{"a", "c"},
+			b:    []string{"a", "b", "c"},
+			want: &SuggestedFix{Pos: Pos{File: "f", Line: 1}, End: Pos{File: "f", Line: 1}, NewText: "a\nb"},
+		},
+		{
+			name: "insert first",
+			a:    []string{"b"},
+			b:    []string{"a", "b"},
+			want: &SuggestedFix{Pos: Pos{File: "f", Line: 1}, End: Pos{File: "f", Line: 1}, NewText: "a\nb"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, diffLines("f", tc.a, tc.b))
+		})
+	}
+}
+
+func TestKeyRange(t *testing.T) {
+	const fieldsYAML = `- name: foo
+  type: group
+  description: >
+    A folded description.
+  fields:
+    - name: bar
+      type: keyword
+      "typo": 'x y'
+`
+	path := filepath.Join(t.TempDir(), "fields.yml")
+	require.NoError(t, os.WriteFile(path, []byte(fieldsYAML), 0o644))
+
+	var fields []pkgspec.Field
+	require.NoError(t, yaml.Unmarshal([]byte(fieldsYAML), &fields))
+	pkgspec.AnnotateFileMetadata(path, &fields)
+	pkgspec.AnnotateFieldPointers(fields)
+	foo, bar := &fields[0], &fields[0].Fields[0]
+
+	testCases := []struct {
+		name       string
+		field      *pkgspec.Field
+		key        string
+		start, end Pos
+	}{
+		{"scalar", foo, "type", Pos{File: path, Line: 2, Col: 3}, Pos{File: path, Line: 2, Col: 14}},
+		{"block scalar", foo, "description", Pos{File: path, Line: 3, Col: 3}, Pos{File: path, Line: 3, Col: 14}},
+		{"sequence", foo, "fields", Pos{File: path, Line: 5, Col: 3}, Pos{File: path, Line: 5, Col: 9}},
+		{"nested", bar, "type", Pos{File: path, Line: 7, Col: 7}, Pos{File: path, Line: 7, Col: 20}},
+		{"quoted", bar, "typo", Pos{File: path, Line: 8, Col: 7}, Pos{File: path, Line: 8, Col: 20}},
+		{"missing", bar, "unit", NewPos(bar.FileMetadata), Pos{}},
+	}
+
+	pass := &Pass{}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end := KeyRange(pass, tc.field, tc.key)
+			assert.Equal(t, tc.start, start)
+			assert.Equal(t, tc.end, end)
+		})
+	}
+}
+
+func TestNodeRange(t *testing.T) {
+	const pipelineYAML = `- set:
+    field: event.kind
+    value: "événement"
+- set:
+    field: event.outcome
+    value: échec
+`
+	var doc yamlv3.Node
+	require.NoError(t, yamlv3.Unmarshal([]byte(pipelineYAML), &doc))
+	seq := doc.Content[0]
+	quoted := seq.Content[0].Content[1].Content[3]
+	plain := seq.Content[1].Content[1].Content[3]
+
+	start, end := NodeRange("default.yml", quoted)
+	assert.Equal(t, Pos{File: "default.yml", Line: 3, Col: 12}, start)
+	assert.Equal(t, Pos{File: "default.yml", Line: 3, Col: 23}, end)
+
+	start, end = NodeRange("default.yml", plain)
+	assert.Equal(t, Pos{File: "default.yml", Line: 6, Col: 12}, start)
+	assert.Equal(t, Pos{File: "default.yml", Line: 6, Col: 17}, end)
+}
+
+func TestPassComplete(t *testing.T) {
+	const (
+		baseFields = "data_stream/logs/fields/base-fields.yml"
+		fields     = "data_stream/logs/fields/fields.yml"
+	)
+	owner := pkgmodel.Owner{
+		Package:    &pkgmodel.Package{},
+		DataStream: &pkgmodel.DataStream{FieldsFiles: []string{baseFields, fields}},
+	}
+
+	base := []pkgspec.Field{{Name: "foo"}}
+	pkgspec.AnnotateFileMetadata("./"+baseFields, &base)
+	pass := &Pass{Fields: []*pkgspec.Field{&base[0]}}
+	assert.False(t, pass.Complete(owner))
+
+	other := []pkgspec.Field{{Name: "bar"}}
+	pkgspec.AnnotateFileMetadata(fields, &other)
+	pass = &Pass{Fields: []*pkgspec.Field{&base[0], &other[0]}}
+	assert.True(t, pass.Complete(owner))
+}
+
+func TestResult(t *testing.T) {
+	type fact struct{ N int }
+
+	provider := &Analyzer{Name: "provider", ResultType: reflect.TypeFor[*fact]()}
+	consumer := &Analyzer{Name: "consumer", Requires: []*Analyzer{provider}, Reads: []reflect.Type{reflect.TypeFor[*fact]()}}
+	other := &Analyzer{Name: "other", ResultType: reflect.TypeFor[*fact]()}
+
+	pass := &Pass{
+		Analyzer: consumer,
+		ResultOf: map[*Analyzer]interface{}{provider: &fact{N: 1}},
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		f, err := Result[*fact](pass, provider)
+		require.NoError(t, err)
+		assert.Equal(t, 1, f.N)
+	})
+
+	t.Run("not_required", func(t *testing.T) {
+		_, err := Result[*fact](pass, other)
+		assert.ErrorContains(t, err, "does not declare it in Requires")
+	})
+
+	t.Run("not_read", func(t *testing.T) {
+		p := &Pass{Analyzer: &Analyzer{Name: "consumer", Requires: []*Analyzer{provider}}, ResultOf: pass.ResultOf}
+		_, err := Result[*fact](p, provider)
+		assert.ErrorContains(t, err, "does not declare it in Reads")
+	})
+
+	t.Run("wrong_type", func(t *testing.T) {
+		p := &Pass{
+			Analyzer: &Analyzer{Name: "consumer", Requires: []*Analyzer{provider}, Reads: []reflect.Type{reflect.TypeFor[fact]()}},
+			ResultOf: pass.ResultOf,
+		}
+		_, err := Result[fact](p, provider)
+		assert.ErrorContains(t, err, "but its ResultType is")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		p := &Pass{Analyzer: consumer, ResultOf: map[*Analyzer]interface{}{}}
+		_, err := Result[*fact](p, provider)
+		assert.ErrorContains(t, err, "is not available")
+	})
+}
+
+func TestValidateRequires(t *testing.T) {
+	noResult := &Analyzer{Name: "noresult"}
+	withResult := &Analyzer{Name: "withresult", ResultType: reflect.TypeFor[int](), Requires: []*Analyzer{noResult}}
+
+	require.NoError(t, ValidateRequires(noResult))
+	assert.ErrorContains(t, ValidateRequires(&Analyzer{Name: "a", Requires: []*Analyzer{noResult}}), "does not declare a ResultType")
+	assert.ErrorContains(t, ValidateRequires(&Analyzer{Name: "a", Requires: []*Analyzer{withResult}}), "does not declare a ResultType")
+	assert.ErrorContains(t, ValidateRequires(&Analyzer{Name: "a", Requires: []*Analyzer{nil}}), "nil entry")
+
+	reads := []reflect.Type{reflect.TypeFor[int]()}
+	require.NoError(t, ValidateRequires(&Analyzer{Name: "a", Requires: []*Analyzer{{Name: "b", ResultType: reflect.TypeFor[int]()}}, Reads: reads}))
+	assert.ErrorContains(t, ValidateRequires(&Analyzer{Name: "a", Reads: reads}), "no analyzer in its Requires")
+}
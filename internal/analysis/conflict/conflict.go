@@ -22,24 +22,25 @@ package conflict
 
 import (
 	"cmp"
-	"errors"
 	"fmt"
+	"reflect"
 	"slices"
 	"strings"
 
-	"github.com/andrewkroh/go-ecs"
 	"github.com/andrewkroh/go-package-spec/pkgspec"
 	"golang.org/x/exp/maps"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
 	"github.com/andrewkroh/fydler/internal/analysis/aliasfact"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
 )
 
 var Analyzer = &analysis.Analyzer{
 	Name:        "conflict",
 	Description: "Detect conflicting field data types across declarations of fields with the same name.",
 	Run:         run,
-	Requires:    []*analysis.Analyzer{aliasfact.Analyzer},
+	Requires:    []*analysis.Analyzer{aliasfact.Analyzer, ecsindexfact.Analyzer},
+	Reads:       []reflect.Type{reflect.TypeFor[*aliasfact.Fact](), reflect.TypeFor[*ecsindexfact.Fact]()},
 }
 
 var (
@@ -57,6 +58,10 @@ func init() {
 	Analyzer.Flags.BoolVar(&ignoreKeywordFamilyConflicts, "ignore-keyword-family", false, "Ignore text type family conflicts (keyword, constant_keyword, and wildcard type definitions are allowed).")
 }
 
+// cancelCheckInterval is the number of fields processed between checks for
+// cancellation.
+const cancelCheckInterval = 1024
+
 func run(pass *analysis.Pass) (interface{}, error) {
 	if err := nonExternalConflicts(pass); err != nil {
 		return nil, err
@@ -69,7 +74,7 @@ func run(pass *analysis.Pass) (interface{}, error) {
 	return nil, nil
 }
 
-func makeDiag(conflicts []*pkgspec.Field, dataTypes []string) *analysis.Diagnostic {
+func makeDiag(pass *analysis.Pass, conflicts []*pkgspec.Field, dataTypes []string) *analysis.Diagnostic {
 	if ignoreTextFamilyConflicts && isTextTypeFamilyConflict(dataTypes...) {
 		return nil
 	}
@@ -81,9 +86,12 @@ func makeDiag(conflicts []*pkgspec.Field, dataTypes []string) *analysis.Diagnost
 	slices.Sort(dataTypes)
 
 	f := conflicts[0]
+	pos, end := analysis.KeyRange(pass, f, "type")
 	diag := &analysis.Diagnostic{
-		Pos:      analysis.NewPos(f.FileMetadata),
+		Pos:      pos,
+		End:      end,
 		Category: "conflict",
+		Field:    f.Name,
 		Message:  fmt.Sprintf("%s has multiple data types (%s)", f.Name, strings.Join(dataTypes, ", ")),
 		Related:  make([]analysis.RelatedInformation, 0, len(conflicts)),
 	}
@@ -99,10 +107,16 @@ func makeDiag(conflicts []*pkgspec.Field, dataTypes []string) *analysis.Diagnost
 // nonExternalConflicts reports conflicts between non-externally defined fields with
 // the same name, but different data types.
 func nonExternalConflicts(pass *analysis.Pass) error {
-	aliasFact := pass.ResultOf[aliasfact.Analyzer].(*aliasfact.Fact)
+	aliasFact, err := analysis.Result[*aliasfact.Fact](pass, aliasfact.Analyzer)
+	if err != nil {
+		return err
+	}
 
 	// Sort by name and type.
 	slices.SortStableFunc(aliasFact.ResolvedAliases, compareFieldByNameAndType)
+	if err := pass.Context.Err(); err != nil {
+		return err
+	}
 
 	var currentKey string
 	var fields []*pkgspec.Field
@@ -113,7 +127,7 @@ func nonExternalConflicts(pass *analysis.Pass) error {
 			dataTypes[string(f.Type)] = struct{}{}
 		}
 		if len(dataTypes) > 1 {
-			if diag := makeDiag(fields, maps.Keys(dataTypes)); diag != nil {
+			if diag := makeDiag(pass, fields, maps.Keys(dataTypes)); diag != nil {
 				pass.Report(*diag)
 			}
 		}
@@ -123,7 +137,13 @@ func nonExternalConflicts(pass *analysis.Pass) error {
 		maps.Clear(dataTypes)
 	}
 
-	for _, f := range aliasFact.ResolvedAliases {
+	for i, f := range aliasFact.ResolvedAliases {
+		if i%cancelCheckInterval == 0 {
+			if err := pass.Context.Err(); err != nil {
+				return err
+			}
+		}
+
 		// The field must have a type to be considered in conflict with another field.
 		if f.Type == "" {
 			continue
@@ -150,8 +170,24 @@ func nonExternalConflicts(pass *analysis.Pass) error {
 // externalECSConflicts reports conflicts between a field's data type and the ECS
 // data type if that field exists in ECS.
 func externalECSConflicts(pass *analysis.Pass) error {
+	ecsIndex, err := analysis.Result[*ecsindexfact.Fact](pass, ecsindexfact.Analyzer)
+	if err != nil {
+		return err
+	}
+
+	ecsFields, err := ecsIndex.Latest()
+	if err != nil {
+		return err
+	}
+
 	// Find conflicts with ECS.
-	for _, f := range pass.Flat {
+	for i, f := range pass.Flat {
+		if i%cancelCheckInterval == 0 {
+			if err := pass.Context.Err(); err != nil {
+				return err
+			}
+		}
+
 		// The field must have a type to be considered in conflict with an external source.
 		if f.Type == "" {
 			continue
@@ -162,12 +198,9 @@ func externalECSConflicts(pass *analysis.Pass) error {
 			continue
 		}
 
-		ecsField, err := ecs.Lookup(f.Name, "")
-		if err != nil {
-			if errors.Is(err, ecs.ErrFieldNotFound) {
-				continue
-			}
-			return err
+		ecsField := ecsFields.Lookup(f.Name)
+		if ecsField == nil {
+			continue
 		}
 
 		if string(f.Type) == ecsField.DataType {
@@ -179,9 +212,12 @@ func externalECSConflicts(pass *analysis.Pass) error {
 		if ignoreKeywordFamilyConflicts && isKeywordTypeFamilyConflict(string(f.Type), ecsField.DataType) {
 			continue
 		}
+		pos, end := analysis.KeyRange(pass, f, "type")
 		pass.Report(analysis.Diagnostic{
-			Pos:      analysis.NewPos(f.FileMetadata),
+			Pos:      pos,
+			End:      end,
 			Category: pass.Analyzer.Name,
+			Field:    f.Name,
 			Message:  fmt.Sprintf("%s field declared as type %s conflicts with the ECS data type %s", f.Name, f.Type, ecsField.DataType),
 		})
 	}
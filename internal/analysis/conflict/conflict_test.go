@@ -38,8 +38,10 @@ func Test(t *testing.T) {
 			Path: "testdata/conflict.yml",
 			Diags: []analysis.Diagnostic{
 				{
-					Pos:      analysis.Pos{File: "testdata/conflict.yml", Line: 2, Col: 3},
+					Pos:      analysis.Pos{File: "testdata/conflict.yml", Line: 3, Col: 3},
+					End:      analysis.Pos{File: "testdata/conflict.yml", Line: 3, Col: 13},
 					Category: "conflict",
+					Field:    "number",
 					Message:  "number has multiple data types (long, short)",
 					Related: []analysis.RelatedInformation{
 						{Pos: analysis.Pos{File: "testdata/conflict.yml", Line: 2, Col: 3}, Message: "long"},
@@ -52,8 +54,10 @@ func Test(t *testing.T) {
 			Path: "testdata/keyword_conflict.yml",
 			Diags: []analysis.Diagnostic{
 				{
-					Pos:      analysis.Pos{File: "testdata/keyword_conflict.yml", Line: 4, Col: 3},
+					Pos:      analysis.Pos{File: "testdata/keyword_conflict.yml", Line: 5, Col: 3},
+					End:      analysis.Pos{File: "testdata/keyword_conflict.yml", Line: 5, Col: 25},
 					Category: "conflict",
+					Field:    "id",
 					Message:  "id has multiple data types (constant_keyword, keyword, wildcard)",
 					Related: []analysis.RelatedInformation{
 						{Pos: analysis.Pos{File: "testdata/keyword_conflict.yml", Line: 4, Col: 3}, Message: "constant_keyword"},
@@ -71,8 +75,10 @@ func Test(t *testing.T) {
 			Path: "testdata/text_conflict.yml",
 			Diags: []analysis.Diagnostic{
 				{
-					Pos:      analysis.Pos{File: "testdata/text_conflict.yml", Line: 4, Col: 3},
+					Pos:      analysis.Pos{File: "testdata/text_conflict.yml", Line: 5, Col: 3},
+					End:      analysis.Pos{File: "testdata/text_conflict.yml", Line: 5, Col: 24},
 					Category: "conflict",
+					Field:    "abstract",
 					Message:  "abstract has multiple data types (match_only_text, text)",
 					Related: []analysis.RelatedInformation{
 						{Pos: analysis.Pos{File: "testdata/text_conflict.yml", Line: 4, Col: 3}, Message: "match_only_text"},
@@ -89,8 +95,10 @@ func Test(t *testing.T) {
 			Path: "testdata/ecs_conflict.yml",
 			Diags: []analysis.Diagnostic{
 				{
-					Pos:      analysis.Pos{File: "testdata/ecs_conflict.yml", Line: 2, Col: 3},
+					Pos:      analysis.Pos{File: "testdata/ecs_conflict.yml", Line: 3, Col: 3},
+					End:      analysis.Pos{File: "testdata/ecs_conflict.yml", Line: 3, Col: 13},
 					Category: "conflict",
+					Field:    "message",
 					Message:  "message field declared as type text conflicts with the ECS data type match_only_text",
 				},
 			},
@@ -15,7 +15,9 @@
 // specific language governing permissions and limitations
 // under the License.
 
-// Package duplicate detects duplicate field declarations within a directory.
+// Package duplicate detects duplicate field declarations within a data
+// stream, transform, or package. Fields files that are not part of a package
+// are grouped by directory.
 package duplicate
 
 import (
@@ -30,7 +32,7 @@ import (
 
 var Analyzer = &analysis.Analyzer{
 	Name:        "duplicate",
-	Description: "Detect duplicate field declarations within a directory.",
+	Description: "Detect duplicate field declarations within a data stream.",
 	Run:         run,
 }
 
@@ -45,9 +47,12 @@ func run(pass *analysis.Pass) (any, error) {
 			}
 
 			field := seenFields[0]
+			pos, end := analysis.KeyRange(pass, field, "name")
 			diag := analysis.Diagnostic{
-				Pos:      analysis.NewPos(field.FileMetadata),
+				Pos:      pos,
+				End:      end,
 				Category: pass.Analyzer.Name,
+				Field:    name,
 				Message:  fmt.Sprintf("%s is declared %d times", name, len(seenFields)),
 			}
 
@@ -62,8 +67,8 @@ func run(pass *analysis.Pass) (any, error) {
 		}
 	}
 	for _, f := range pass.Flat {
-		// When the directory changes flush the duplicates.
-		if dir := filepath.Dir(f.FilePath()); currentDir != dir {
+		// When the owner changes flush the duplicates.
+		if dir := ownerDir(pass, f); currentDir != dir {
 			// Reset
 			flush()
 			maps.Clear(seen)
@@ -78,3 +83,13 @@ func run(pass *analysis.Pass) (any, error) {
 	flush()
 	return nil, nil
 }
+
+// ownerDir returns the directory of the data stream, transform, or package
+// that owns the field's file, or the file's directory if it is not part of a
+// package.
+func ownerDir(pass *analysis.Pass, f *pkgspec.Field) string {
+	if owner, found := pass.Packages.Owner(f.FilePath()); found {
+		return owner.Dir()
+	}
+	return filepath.Dir(f.FilePath())
+}
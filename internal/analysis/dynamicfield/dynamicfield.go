@@ -42,9 +42,12 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		// Without 'object_type' fleet creates a static mapping for a field whose literal
 		// name includes '*' (e.g. 'tags.*').
 		if f.Type == "object" && f.ObjectType == "" {
+			pos, end := analysis.KeyRange(pass, f, "type")
 			pass.Report(analysis.Diagnostic{
-				Pos:      analysis.NewPos(f.FileMetadata),
+				Pos:      pos,
+				End:      end,
 				Category: pass.Analyzer.Name,
+				Field:    f.Name,
 				Message: fmt.Sprintf("%s field is meant to be a dynamic mapping, but is missing an 'object_type' "+
 					"so it will never be a dynamic mapping", f.Name),
 			})
@@ -52,9 +55,12 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		}
 
 		if f.Type == "" {
+			pos, end := analysis.KeyRange(pass, f, "name")
 			pass.Report(analysis.Diagnostic{
-				Pos:      analysis.NewPos(f.FileMetadata),
+				Pos:      pos,
+				End:      end,
 				Category: pass.Analyzer.Name,
+				Field:    f.Name,
 				Message: fmt.Sprintf("%s field is meant to be a dynamic mapping, but does not specify a 'type' "+
 					"so it will never be a dynamic mapping", f.Name),
 			})
@@ -24,11 +24,13 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
 
 	"github.com/andrewkroh/go-ecs"
 	"github.com/andrewkroh/go-package-spec/pkgspec"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
 	"github.com/andrewkroh/fydler/internal/analysis/ecsversionfact"
 )
 
@@ -37,8 +39,10 @@ var Analyzer = &analysis.Analyzer{
 	Description: "Gathers the external ECS definition for fields. " +
 		"It reports a diagnostic if the field is not a leaf field in the " +
 		"version of ECS declared in the _dev/build/build.yml file.",
-	Run:      run,
-	Requires: []*analysis.Analyzer{ecsversionfact.Analyzer},
+	Run:        run,
+	Requires:   []*analysis.Analyzer{ecsversionfact.Analyzer, ecsindexfact.Analyzer},
+	Reads:      []reflect.Type{reflect.TypeFor[*ecsversionfact.Fact](), reflect.TypeFor[*ecsindexfact.Fact]()},
+	ResultType: reflect.TypeFor[*Fact](),
 }
 
 type Fact struct {
@@ -48,7 +52,15 @@ type Fact struct {
 func run(pass *analysis.Pass) (interface{}, error) {
 	// Only log a Diagnostic once per directory.
 	unknownECSVersion := map[string]struct{}{}
-	ecsVersionsFact := pass.ResultOf[ecsversionfact.Analyzer].(*ecsversionfact.Fact)
+	ecsVersionsFact, err := analysis.Result[*ecsversionfact.Fact](pass, ecsversionfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+	ecsIndex, err := analysis.Result[*ecsindexfact.Fact](pass, ecsindexfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+
 	fact := &Fact{EnrichedFlat: make([]*pkgspec.Field, 0, len(pass.Flat))}
 
 	for _, f := range pass.Flat {
@@ -57,27 +69,22 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			continue
 		}
 
-		// If the ecsVersion is not found, then the ecs.Lookup() will use data
-		// from the latest ECS version. The ecsversionfact will have logged a
-		// diagnostic about that problem.
+		// If the ecsVersion is not found, then the table for the latest ECS
+		// version is used. The ecsversionfact will have logged a diagnostic
+		// about that problem.
 		ecsVersion := ecsVersionsFact.ECSVersion(f.FilePath())
 
 		dir := filepath.Dir(f.FilePath())
-		ecsField, err := ecs.Lookup(f.Name, ecsVersion)
+		ecsFields, err := ecsIndex.Table(ecsVersion)
 		if err != nil {
 			switch {
-			case errors.Is(err, ecs.ErrFieldNotFound):
-				pass.Report(analysis.Diagnostic{
-					Pos:      analysis.NewPos(f.FileMetadata),
-					Category: pass.Analyzer.Name,
-					Message:  fmt.Sprintf("%s is declared with 'external: ecs' but this field does not exist in ECS version %q", f.Name, ecsVersion),
-				})
 			case errors.Is(err, ecs.ErrVersionNotFound):
 				if _, found := unknownECSVersion[dir]; !found {
 					unknownECSVersion[dir] = struct{}{}
 					pass.Report(analysis.Diagnostic{
 						Pos:      analysis.NewPos(f.FileMetadata),
 						Category: pass.Analyzer.Name,
+						Field:    f.Name,
 						Message:  fmt.Sprintf("%s is declared with 'external: ecs' using ECS version %q, but this version is unknown this tool", f.Name, ecsVersion),
 					})
 				}
@@ -87,6 +94,7 @@ func run(pass *analysis.Pass) (interface{}, error) {
 					pass.Report(analysis.Diagnostic{
 						Pos:      analysis.NewPos(f.FileMetadata),
 						Category: pass.Analyzer.Name,
+						Field:    f.Name,
 						Message:  fmt.Sprintf("%s is declared with 'external: ecs' using ECS version %q, but that is an invalid version (%s)", f.Name, ecsVersion, err),
 					})
 				}
@@ -98,6 +106,19 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			continue
 		}
 
+		ecsField := ecsFields.Lookup(f.Name)
+		if ecsField == nil {
+			pos, end := analysis.KeyRange(pass, f, "name")
+			pass.Report(analysis.Diagnostic{
+				Pos:      pos,
+				End:      end,
+				Category: pass.Analyzer.Name,
+				Field:    f.Name,
+				Message:  fmt.Sprintf("%s is declared with 'external: ecs' but this field does not exist in ECS version %q", f.Name, ecsVersion),
+			})
+			continue
+		}
+
 		// Copy-on-write.
 		{
 			tmp := *f
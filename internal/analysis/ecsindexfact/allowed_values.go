@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ecsindexfact
+
+// allowedValues contains the allowed values of the ECS categorization
+// fields. The ECS field definitions embedded in go-ecs do not include
+// allowed_values so they are maintained here. They reflect the latest ECS
+// release. Values that were added later than the oldest embedded release are
+// listed in allowedValueVersions.
+//
+// https://www.elastic.co/guide/en/ecs/current/ecs-allowed-values-event-kind.html
+var allowedValues = map[string][]string{
+	"event.kind": {
+		"alert",
+		"asset",
+		"enrichment",
+		"event",
+		"metric",
+		"state",
+		"pipeline_error",
+		"signal",
+	},
+	"event.category": {
+		"api",
+		"authentication",
+		"configuration",
+		"database",
+		"driver",
+		"email",
+		"file",
+		"host",
+		"iam",
+		"intrusion_detection",
+		"library",
+		"malware",
+		"network",
+		"package",
+		"process",
+		"registry",
+		"session",
+		"threat",
+		"vulnerability",
+		"web",
+	},
+	"event.type": {
+		"access",
+		"admin",
+		"allowed",
+		"change",
+		"connection",
+		"creation",
+		"deletion",
+		"denied",
+		"end",
+		"error",
+		"group",
+		"indicator",
+		"info",
+		"installation",
+		"protocol",
+		"start",
+		"user",
+	},
+	"event.outcome": {
+		"failure",
+		"success",
+		"unknown",
+	},
+}
+
+// expectedEventTypes maps each event.category value to the event.type values
+// that ECS expects to be used with it. Like allowedValues, it reflects the
+// latest ECS release.
+//
+// https://www.elastic.co/guide/en/ecs/current/ecs-allowed-values-event-category.html
+var expectedEventTypes = map[string][]string{
+	"api":                 {"access", "admin", "allowed", "change", "creation", "deletion", "denied", "end", "info", "start", "user"},
+	"authentication":      {"start", "end", "info"},
+	"configuration":       {"access", "change", "creation", "deletion", "info"},
+	"database":            {"access", "change", "info", "error"},
+	"driver":              {"change", "end", "info", "start"},
+	"email":               {"info"},
+	"file":                {"change", "creation", "deletion", "info"},
+	"host":                {"access", "change", "end", "info", "start"},
+	"iam":                 {"admin", "change", "creation", "deletion", "group", "info", "user"},
+	"intrusion_detection": {"allowed", "denied", "info"},
+	"library":             {"start"},
+	"malware":             {"info"},
+	"network":             {"access", "allowed", "connection", "denied", "end", "info", "protocol", "start"},
+	"package":             {"access", "change", "deletion", "info", "installation", "start"},
+	"process":             {"access", "change", "end", "info", "start"},
+	"registry":            {"access", "change", "creation", "deletion"},
+	"session":             {"start", "end", "info"},
+	"threat":              {"indicator"},
+	"vulnerability":       {"info"},
+	"web":                 {"access", "error", "info"},
+}
+
+// allowedValueVersions maps field names to the allowed values that were added
+// after the oldest embedded ECS release and the release that added each one.
+//
+// https://github.com/elastic/ecs/blob/main/CHANGELOG.md
+var allowedValueVersions = map[string]map[string]string{
+	"event.category": {
+		"api":     "8.16.0",
+		"email":   "8.7.0",
+		"library": "8.16.0",
+	},
+}
+
+// versionAllowedValues returns the allowed values and the expected event types
+// of the embedded ECS version. Values added in a later release are omitted.
+func versionAllowedValues(version string) (allowed, expected map[string][]string) {
+	since := func(field, value string) bool {
+		v, found := allowedValueVersions[field][value]
+		return !found || compareVersions(normalizeVersion(version), v) >= 0
+	}
+
+	allowed = make(map[string][]string, len(allowedValues))
+	for field, values := range allowedValues {
+		for _, value := range values {
+			if since(field, value) {
+				allowed[field] = append(allowed[field], value)
+			}
+		}
+	}
+
+	expected = make(map[string][]string, len(expectedEventTypes))
+	for category, types := range expectedEventTypes {
+		if since("event.category", category) {
+			expected[category] = types
+		}
+	}
+	return allowed, expected
+}
@@ -0,0 +1,239 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package ecsindexfact provides a fact containing indexed ECS field tables.
+// Each table is built once per ECS version and shared by all analyzers that
+// consume ECS definitions, rather than each analyzer querying ECS per field.
+// The definitions come from go-ecs unless a schema was loaded from disk with
+// -ecsindexfact.schema.
+package ecsindexfact
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/andrewkroh/go-ecs"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:        "ecsindexfact",
+	Description: "Builds indexed ECS field tables that are shared by the analyzers that use ECS.",
+	Run:         run,
+	ResultType:  reflect.TypeFor[*Fact](),
+}
+
+var schemaPath string
+
+func init() {
+	Analyzer.Flags.StringVar(&schemaPath, "schema", "", "Load ECS field definitions from an ecs_flat.yml file, "+
+		"prefixed with <version>= unless it is in an ECS repository checkout, or from a directory of "+
+		"<version>/ecs_flat.yml files. These take precedence over the embedded ECS definitions.")
+}
+
+// Fact provides access to ECS field tables. Tables are built on first use
+// and then cached for the remainder of the run.
+type Fact struct {
+	mu     sync.Mutex
+	tables map[string]*tableResult // Keyed by the requested version.
+
+	// Schemas loaded from disk take precedence over the definitions embedded
+	// in go-ecs.
+	schemas             map[string]*schema // Keyed by version without a "v" prefix.
+	latestSchemaVersion string             // Used when no version is requested.
+
+	versionsOnce sync.Once
+	versions     []string
+}
+
+type tableResult struct {
+	table *Table
+	err   error
+}
+
+// Table is an index of the fields in a single version of ECS. It must not be
+// modified.
+type Table struct {
+	// Fields maps field names to their ECS definitions.
+	Fields map[string]*ecs.Field
+
+	// RootNamespaces contains the first segment of all dotted ECS field
+	// names (e.g. "host" for host.name).
+	RootNamespaces map[string]struct{}
+
+	// ArrayFields contains the names of fields that use array normalization.
+	ArrayFields map[string]struct{}
+
+	// AllowedValues maps field names to their list of allowed values in this
+	// version of ECS. Only fields that exist in this version of ECS are
+	// included. The values come from the schema file when the version was
+	// loaded from disk.
+	AllowedValues map[string][]string
+
+	// ExpectedEventTypes maps each allowed value of event.category to the
+	// event.type values expected to be used with it. It is empty when this
+	// version of ECS does not have event.category and event.type.
+	ExpectedEventTypes map[string][]string
+}
+
+// Latest returns the table for the latest version of ECS.
+func (f *Fact) Latest() (*Table, error) {
+	return f.Table("")
+}
+
+// Table returns the table for the given ECS version. An empty version
+// selects the latest version of ECS (see LatestVersion). The errors returned
+// by ecs.Fields, such as ecs.ErrVersionNotFound and ecs.ErrInvalidVersion,
+// are returned as is.
+func (f *Fact) Table(version string) (*Table, error) {
+	if version == "" {
+		version = f.LatestVersion()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r, found := f.tables[version]; found {
+		return r.table, r.err
+	}
+
+	t, err := f.newTable(version)
+	f.tables[version] = &tableResult{table: t, err: err}
+	return t, err
+}
+
+// LatestVersion returns the latest ECS version (e.g. 8.17.0). It is the
+// version of the latest schema loaded from disk, if any, or else the newest
+// version embedded in go-ecs. It returns an empty string if no version is
+// known.
+func (f *Fact) LatestVersion() string {
+	if f.latestSchemaVersion != "" {
+		return f.latestSchemaVersion
+	}
+	if versions := f.Versions(); len(versions) > 0 {
+		return versions[len(versions)-1]
+	}
+	return ""
+}
+
+// Versions returns the known ECS versions, both embedded in go-ecs and loaded
+// from disk, from oldest to newest. It must not be modified.
+func (f *Fact) Versions() []string {
+	f.versionsOnce.Do(func() {
+		versions := embeddedVersions()
+		for v := range f.schemas {
+			if !slices.Contains(versions, v) {
+				versions = append(versions, v)
+			}
+		}
+		slices.SortFunc(versions, compareVersions)
+		f.versions = versions
+	})
+	return f.versions
+}
+
+// Lookup returns the definition of the named field or nil if it does not
+// exist in ECS.
+func (t *Table) Lookup(name string) *ecs.Field {
+	return t.Fields[name]
+}
+
+// IsArray returns true if the named field uses array normalization.
+func (t *Table) IsArray(name string) bool {
+	_, found := t.ArrayFields[name]
+	return found
+}
+
+// IsRootNamespace returns true if ns is the root namespace of any ECS field.
+func (t *Table) IsRootNamespace(ns string) bool {
+	_, found := t.RootNamespaces[ns]
+	return found
+}
+
+// Namespace returns the root namespace of a field name. It returns an empty
+// string for field names that do not contain a dot.
+func Namespace(fieldName string) string {
+	idx := strings.IndexByte(fieldName, '.')
+	if idx == -1 {
+		return ""
+	}
+	return fieldName[:idx]
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	fact := &Fact{tables: map[string]*tableResult{}}
+	if schemaPath != "" {
+		var err error
+		if fact.schemas, fact.latestSchemaVersion, err = loadSchemas(schemaPath); err != nil {
+			return nil, fmt.Errorf("failed to load ECS schema: %w", err)
+		}
+	}
+
+	// The latest version is used by most consumers so build it eagerly. This
+	// also surfaces any problem with the embedded ECS data immediately.
+	if _, err := fact.Latest(); err != nil {
+		return nil, err
+	}
+
+	return fact, nil
+}
+
+func (f *Fact) newTable(version string) (*Table, error) {
+	var fields map[string]*ecs.Field
+	var allowed, expected map[string][]string
+	if s, found := f.schemas[normalizeVersion(version)]; found {
+		fields, allowed, expected = s.fields, s.allowedValues, s.expectedEventTypes
+	} else {
+		var err error
+		if fields, err = ecs.Fields(version); err != nil {
+			return nil, err
+		}
+		allowed, expected = versionAllowedValues(version)
+	}
+
+	t := &Table{
+		Fields:             fields,
+		RootNamespaces:     map[string]struct{}{},
+		ArrayFields:        map[string]struct{}{},
+		AllowedValues:      map[string][]string{},
+		ExpectedEventTypes: map[string][]string{},
+	}
+	for name, field := range fields {
+		if ns := Namespace(name); ns != "" {
+			t.RootNamespaces[ns] = struct{}{}
+		}
+		if field.Array {
+			t.ArrayFields[name] = struct{}{}
+		}
+	}
+	for name, values := range allowed {
+		if _, found := fields[name]; found {
+			t.AllowedValues[name] = values
+		}
+	}
+	if fields["event.category"] != nil && fields["event.type"] != nil {
+		for category, types := range expected {
+			t.ExpectedEventTypes[category] = types
+		}
+	}
+
+	return t, nil
+}
@@ -0,0 +1,228 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ecsindexfact
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/andrewkroh/go-ecs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+// newFact runs the analyzer. It does not use fydler.Run because fydler
+// imports this package to load ECS schemas.
+func newFact(t *testing.T) *Fact {
+	t.Helper()
+
+	result, err := Analyzer.Run(&analysis.Pass{Analyzer: Analyzer})
+	require.NoError(t, err)
+	return result.(*Fact)
+}
+
+// newSchemaFact runs the analyzer with a schema loaded from path.
+func newSchemaFact(t *testing.T, path string) *Fact {
+	t.Helper()
+
+	schemaPath = path
+	t.Cleanup(func() { schemaPath = "" })
+	return newFact(t)
+}
+
+func Test(t *testing.T) {
+	fact := newFact(t)
+
+	latest, err := fact.Latest()
+	require.NoError(t, err)
+
+	// Tables are built once and then reused.
+	again, err := fact.Table("")
+	require.NoError(t, err)
+	assert.Same(t, latest, again)
+
+	if assert.NotNil(t, latest.Lookup("host.name")) {
+		assert.Equal(t, "keyword", latest.Lookup("host.name").DataType)
+	}
+	assert.Nil(t, latest.Lookup("host.CustomField"))
+
+	assert.True(t, latest.IsRootNamespace("host"))
+	assert.False(t, latest.IsRootNamespace("host.name"))
+	assert.False(t, latest.IsRootNamespace("@timestamp"))
+
+	// The latest version depends on go-ecs so only check that it is usable.
+	latestVersion := fact.LatestVersion()
+	assert.Equal(t, latestVersion, fact.Versions()[len(fact.Versions())-1])
+	versionTable, err := fact.Table(latestVersion)
+	require.NoError(t, err)
+	assert.NotNil(t, versionTable.Lookup("host.name"))
+
+	assert.True(t, latest.IsArray("related.ip"))
+	assert.False(t, latest.IsArray("host.name"))
+
+	assert.Contains(t, latest.AllowedValues["event.outcome"], "success")
+	assert.NotContains(t, latest.AllowedValues, "host.name")
+	assert.Equal(t, []string{"indicator"}, latest.ExpectedEventTypes["threat"])
+
+	_, err = fact.Table("1.0")
+	assert.True(t, errors.Is(err, ecs.ErrInvalidVersion), "unexpected error: %v", err)
+
+	// Errors are cached too.
+	_, err = fact.Table("1.0")
+	assert.True(t, errors.Is(err, ecs.ErrInvalidVersion), "unexpected error: %v", err)
+}
+
+func TestVersionAllowedValues(t *testing.T) {
+	allowed, expected := versionAllowedValues("v8.6.0")
+	assert.NotContains(t, allowed["event.category"], "email")
+	assert.NotContains(t, expected, "email")
+	assert.Contains(t, allowed["event.category"], "authentication")
+	assert.Equal(t, allowedValues["event.outcome"], allowed["event.outcome"])
+
+	allowed, expected = versionAllowedValues("8.16.0")
+	assert.Equal(t, allowedValues["event.category"], allowed["event.category"])
+	assert.Equal(t, expectedEventTypes, expected)
+}
+
+func TestNamespace(t *testing.T) {
+	assert.Equal(t, "host", Namespace("host.geo.location"))
+	assert.Equal(t, "", Namespace("@timestamp"))
+}
+
+func TestVersions(t *testing.T) {
+	fact := newFact(t)
+
+	versions := fact.Versions()
+	require.NotEmpty(t, versions)
+	assert.True(t, slices.IsSortedFunc(versions, compareVersions), versions)
+	for _, v := range versions {
+		_, err := ecs.Lookup("@timestamp", v)
+		assert.NoError(t, err, v)
+	}
+}
+
+func TestProbeVersions(t *testing.T) {
+	known := []string{
+		"1.0.0", "1.0.1", "1.12.0", "1.12.3",
+		"8.0.0", "8.9.0", "8.17.0", "8.17.1",
+		"9.0.0", "9.2.0",
+	}
+	versions := probeVersions(func(v string) bool { return slices.Contains(known, v) })
+
+	// A gap of maxMissingPatches patch versions ends the probing of a minor
+	// version. Gaps in minor and major versions do not.
+	assert.Equal(t, []string{
+		"1.0.0", "1.0.1", "1.12.0",
+		"8.0.0", "8.9.0", "8.17.0", "8.17.1",
+		"9.0.0", "9.2.0",
+	}, versions)
+}
+
+func TestLoadSchemas(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		fact := newSchemaFact(t, "8.99.1-rfc=testdata/ecs_flat.yml")
+		assert.Equal(t, "8.99.1-rfc", fact.LatestVersion())
+		assert.Contains(t, fact.Versions(), "8.99.1-rfc")
+
+		for _, version := range []string{"", "8.99.1-rfc", "v8.99.1-rfc"} {
+			table, err := fact.Table(version)
+			require.NoError(t, err)
+
+			assert.NotNil(t, table.Lookup("rfc.new_field"), version)
+			assert.True(t, table.IsArray("rfc.new_field"), version)
+			assert.True(t, table.IsRootNamespace("rfc"), version)
+			assert.Nil(t, table.Lookup("source.ip"), version)
+			assert.Equal(t, []string{"event", "rfc_kind"}, table.AllowedValues["event.kind"], version)
+			assert.Equal(t, map[string][]string{"authentication": {"start", "end"}}, table.ExpectedEventTypes, version)
+		}
+
+		// Embedded versions are still available.
+		table, err := fact.Table("8.9.0")
+		require.NoError(t, err)
+		assert.NotNil(t, table.Lookup("source.ip"))
+	})
+
+	t.Run("repository", func(t *testing.T) {
+		// An ecs_flat.yml without a version prefix uses the version of the
+		// ECS repository that contains it.
+		root := t.TempDir()
+		path := filepath.Join(root, "generated", "ecs", "ecs_flat.yml")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		data, err := os.ReadFile("testdata/ecs_flat.yml")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "version"), []byte("8.99.2\n"), 0o644))
+
+		fact := newSchemaFact(t, path)
+		assert.Equal(t, "8.99.2", fact.LatestVersion())
+		for _, version := range []string{"", "8.99.2"} {
+			table, err := fact.Table(version)
+			require.NoError(t, err)
+			assert.NotNil(t, table.Lookup("rfc.new_field"), version)
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		fact := newSchemaFact(t, "testdata/schemas")
+		assert.Subset(t, fact.Versions(), []string{"8.99.0", "8.100.0"})
+
+		latest, err := fact.Latest()
+		require.NoError(t, err)
+		assert.Equal(t, "wildcard", latest.Lookup("host.name").DataType)
+		assert.Equal(t, "8.100.0", fact.LatestVersion())
+
+		table, err := fact.Table("v8.99.0")
+		require.NoError(t, err)
+		assert.Equal(t, "keyword", table.Lookup("host.name").DataType)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := loadSchemas("testdata/missing.yml")
+		assert.Error(t, err)
+
+		_, _, err = loadSchemas("8.0.0=testdata/schemas")
+		assert.ErrorContains(t, err, "a version cannot be specified")
+
+		_, _, err = loadSchemas("testdata/schemas/8.99.0")
+		assert.ErrorContains(t, err, "no <version>/ecs_flat.yml files found")
+
+		path := filepath.Join(t.TempDir(), "ecs_flat.yml")
+		data, err := os.ReadFile("testdata/ecs_flat.yml")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		_, _, err = loadSchemas(path)
+		assert.ErrorContains(t, err, "cannot determine the ECS version")
+
+		schemaPath = path
+		t.Cleanup(func() { schemaPath = "" })
+		_, err = Analyzer.Run(&analysis.Pass{Analyzer: Analyzer})
+		assert.ErrorContains(t, err, "failed to load ECS schema")
+	})
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Negative(t, compareVersions("8.9.0", "8.10.0"))
+	assert.Positive(t, compareVersions("9.0.0", "8.17.0"))
+	assert.Zero(t, compareVersions("8.17.0", "8.17.0"))
+	assert.Negative(t, compareVersions("8.17", "8.17.0"))
+}
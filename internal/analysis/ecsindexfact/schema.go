@@ -0,0 +1,252 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ecsindexfact
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andrewkroh/go-ecs"
+	"gopkg.in/yaml.v3"
+)
+
+// schema is an ECS schema loaded from disk.
+type schema struct {
+	fields             map[string]*ecs.Field
+	allowedValues      map[string][]string
+	expectedEventTypes map[string][]string // Keyed by event.category value.
+}
+
+// flatField is an entry from ecs_flat.yml.
+type flatField struct {
+	Type          string   `yaml:"type"`
+	Description   string   `yaml:"description"`
+	Pattern       string   `yaml:"pattern"`
+	Normalize     []string `yaml:"normalize"`
+	AllowedValues []struct {
+		Name               string   `yaml:"name"`
+		ExpectedEventTypes []string `yaml:"expected_event_types"`
+	} `yaml:"allowed_values"`
+}
+
+// loadSchemas loads ECS field definitions from disk so that they are used
+// instead of the definitions embedded in go-ecs. The path may be:
+//
+//   - An ecs_flat.yml file. It becomes the latest version of ECS. It is
+//     registered as the version given by a "<version>=" prefix or, without a
+//     prefix, as the version read from the version file of the ECS
+//     repository that contains it (e.g. generated/ecs/ecs_flat.yml).
+//   - A directory containing <version>/ecs_flat.yml files. Each is registered
+//     as its version, and the highest version becomes the latest.
+//
+// It returns the schemas keyed by version without a "v" prefix and the
+// latest version.
+func loadSchemas(path string) (schemas map[string]*schema, latest string, err error) {
+	var version string
+	if v, p, found := strings.Cut(path, "="); found {
+		version, path = v, p
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !info.IsDir() {
+		s, err := readSchema(path)
+		if err != nil {
+			return nil, "", err
+		}
+		if version == "" {
+			if version, err = repositoryVersion(path); err != nil {
+				return nil, "", err
+			}
+		}
+		latest = normalizeVersion(version)
+		return map[string]*schema{latest: s}, latest, nil
+	}
+
+	if version != "" {
+		return nil, "", fmt.Errorf("a version cannot be specified for the schema directory %s", path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	schemas = map[string]*schema{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		s, err := readSchema(filepath.Join(path, e.Name(), "ecs_flat.yml"))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, "", err
+		}
+		v := normalizeVersion(e.Name())
+		schemas[v] = s
+		if latest == "" || compareVersions(v, latest) > 0 {
+			latest = v
+		}
+	}
+	if len(schemas) == 0 {
+		return nil, "", fmt.Errorf("no <version>/ecs_flat.yml files found in %s", path)
+	}
+	return schemas, latest, nil
+}
+
+// versionFileDepth is the number of parent directories of an ecs_flat.yml
+// file that are searched for the version file of an ECS repository. The
+// repository layout is <root>/generated/ecs/ecs_flat.yml.
+const versionFileDepth = 3
+
+// repositoryVersion returns the ECS version of an ecs_flat.yml file from the
+// version file at the root of the ECS repository that contains it.
+func repositoryVersion(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(abs)
+	for i := 0; i < versionFileDepth; i++ {
+		data, err := os.ReadFile(filepath.Join(dir, "version"))
+		if err == nil {
+			if v := strings.TrimSpace(string(data)); v != "" {
+				return v, nil
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		dir = filepath.Dir(dir)
+	}
+	return "", fmt.Errorf("cannot determine the ECS version of %s; prefix the path with <version>=", path)
+}
+
+func readSchema(path string) (*schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var flat map[string]flatField
+	if err = yaml.Unmarshal(data, &flat); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", path, err)
+	}
+	if len(flat) == 0 {
+		return nil, fmt.Errorf("no fields found in %s", path)
+	}
+
+	s := &schema{
+		fields:             make(map[string]*ecs.Field, len(flat)),
+		allowedValues:      map[string][]string{},
+		expectedEventTypes: map[string][]string{},
+	}
+	for name, f := range flat {
+		s.fields[name] = &ecs.Field{
+			Name:        name,
+			DataType:    f.Type,
+			Array:       slices.Contains(f.Normalize, "array"),
+			Pattern:     f.Pattern,
+			Description: f.Description,
+		}
+		for _, v := range f.AllowedValues {
+			s.allowedValues[name] = append(s.allowedValues[name], v.Name)
+			if name == "event.category" && len(v.ExpectedEventTypes) > 0 {
+				s.expectedEventTypes[v.Name] = v.ExpectedEventTypes
+			}
+		}
+	}
+	return s, nil
+}
+
+// go-ecs does not list the versions that it embeds so they are found by
+// probing for releases. Every major and minor version up to maxProbedMajor
+// and maxProbedMinor is probed because go-ecs need not embed every release
+// (and ECS skipped from 1.x to 8.x). Patch versions are probed until
+// maxMissingPatches in a row are not found.
+const (
+	maxProbedMajor    = 20
+	maxProbedMinor    = 50
+	maxMissingPatches = 2
+)
+
+// embeddedVersions returns the ECS versions embedded in go-ecs from oldest to
+// newest.
+func embeddedVersions() []string {
+	return probeVersions(func(version string) bool {
+		// Lookup is used rather than Fields because it is cheap regardless
+		// of how the definitions are stored.
+		_, err := ecs.Lookup("@timestamp", version)
+		return !errors.Is(err, ecs.ErrVersionNotFound) && !errors.Is(err, ecs.ErrInvalidVersion)
+	})
+}
+
+// probeVersions returns the versions for which exists returns true from
+// oldest to newest.
+func probeVersions(exists func(version string) bool) []string {
+	var versions []string
+	for major := 1; major <= maxProbedMajor; major++ {
+		for minor := 0; minor <= maxProbedMinor; minor++ {
+			for patch, missingPatches := 0, 0; missingPatches < maxMissingPatches; patch++ {
+				v := fmt.Sprintf("%d.%d.%d", major, minor, patch)
+				if !exists(v) {
+					missingPatches++
+					continue
+				}
+				versions = append(versions, v)
+				missingPatches = 0
+			}
+		}
+	}
+	return versions
+}
+
+func normalizeVersion(v string) string {
+	return strings.TrimPrefix(v, "v")
+}
+
+// compareVersions compares dotted numeric versions. Non-numeric parts are
+// compared as strings.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		var c int
+		if aErr == nil && bErr == nil {
+			c = an - bn
+		} else {
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return len(as) - len(bs)
+}
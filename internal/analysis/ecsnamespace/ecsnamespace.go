@@ -27,23 +27,29 @@
 package ecsnamespace
 
 import (
-	"errors"
 	"fmt"
-	"strings"
-
-	"github.com/andrewkroh/go-ecs"
+	"reflect"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
 )
 
 var Analyzer = &analysis.Analyzer{
 	Name:        "ecsnamespace",
 	Description: "Detect fields being added to namespaces controlled by ECS.",
 	Run:         run,
+	Requires:    []*analysis.Analyzer{ecsindexfact.Analyzer},
+	Reads:       []reflect.Type{reflect.TypeFor[*ecsindexfact.Fact]()},
 }
 
 func run(pass *analysis.Pass) (interface{}, error) {
-	namespaces, err := ecsRootNamespaces()
+	ecsIndex, err := analysis.Result[*ecsindexfact.Fact](pass, ecsindexfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine the ECS namespaces by using the latest version of ECS.
+	ecsFields, err := ecsIndex.Latest()
 	if err != nil {
 		return nil, err
 	}
@@ -55,54 +61,23 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		}
 
 		// Ignore fields in ECS even if they are not using 'external: ecs'.
-		ecsField, err := ecs.Lookup(f.Name, "")
-		if err != nil && !errors.Is(err, ecs.ErrFieldNotFound) {
-			// Should never happen.
-			return nil, err
-		}
-		if ecsField != nil {
+		if ecsFields.Lookup(f.Name) != nil {
 			continue
 		}
 
-		ns := namespace(f.Name)
-		_, isECSManaged := namespaces[ns]
-		if !isECSManaged {
+		if !ecsFields.IsRootNamespace(ecsindexfact.Namespace(f.Name)) {
 			continue
 		}
 
+		pos, end := analysis.KeyRange(pass, f, "name")
 		pass.Report(analysis.Diagnostic{
-			Pos:      analysis.NewPos(f.FileMetadata),
+			Pos:      pos,
+			End:      end,
 			Category: pass.Analyzer.Name,
+			Field:    f.Name,
 			Message:  fmt.Sprintf("%s is defined in an ECS managed namespace, custom fields must use the dataset's namespace", f.Name),
 		})
 	}
 
 	return nil, nil
 }
-
-func namespace(fieldName string) string {
-	idx := strings.IndexByte(fieldName, '.')
-	if idx == -1 {
-		return ""
-	}
-	return fieldName[:idx]
-}
-
-func ecsRootNamespaces() (map[string]struct{}, error) {
-	// Determine the ECS namespaces by using the latest version of ECS.
-	fields, err := ecs.Fields("")
-	if err != nil {
-		return nil, err
-	}
-
-	namespaces := map[string]struct{}{}
-	for name := range fields {
-		ns := namespace(name)
-		if ns == "" {
-			continue
-		}
-		namespaces[ns] = struct{}{}
-	}
-
-	return namespaces, nil
-}
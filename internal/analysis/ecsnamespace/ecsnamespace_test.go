@@ -38,7 +38,9 @@ func Test(t *testing.T) {
 			Diags: []analysis.Diagnostic{
 				{
 					Pos:      analysis.Pos{File: "testdata/fields.yml", Line: 2, Col: 3},
+					End:      analysis.Pos{File: "testdata/fields.yml", Line: 2, Col: 25},
 					Category: "ecsnamespace",
+					Field:    "host.CustomField",
 					Message:  "host.CustomField is defined in an ECS managed namespace, custom fields must use the dataset's namespace",
 				},
 			},
@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package ecsupgrade provides an analyzer that reports how upgrading the ECS
+// version referenced by a package's _dev/build/build.yml would change the
+// fields that use 'external: ecs'. It only runs when a target version is
+// given with the -ecsupgrade.to flag.
+package ecsupgrade
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsversionfact"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "ecsupgrade",
+	Description: "Report how upgrading to the ECS version given by -ecsupgrade.to changes " +
+		"fields that use 'external: ecs' (type changes, removals, array normalization, and descriptions).",
+	Run:      run,
+	Requires: []*analysis.Analyzer{ecsversionfact.Analyzer, ecsindexfact.Analyzer},
+	Reads:    []reflect.Type{reflect.TypeFor[*ecsversionfact.Fact](), reflect.TypeFor[*ecsindexfact.Fact]()},
+}
+
+var targetVersion string
+
+func init() {
+	Analyzer.Flags.StringVar(&targetVersion, "to", "", "ECS version to compare against (e.g. 8.17.0). The analyzer does nothing when empty.")
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if targetVersion == "" {
+		return nil, nil
+	}
+
+	ecsVersionsFact, err := analysis.Result[*ecsversionfact.Fact](pass, ecsversionfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+	ecsIndex, err := analysis.Result[*ecsindexfact.Fact](pass, ecsindexfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := ecsIndex.Table(targetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target ECS version %q: %w", targetVersion, err)
+	}
+
+	for _, f := range pass.Flat {
+		if f.External != "ecs" {
+			continue
+		}
+
+		// Fields without a known current version or definition are reported
+		// by ecsversionfact and ecsdefinitionfact.
+		version := ecsVersionsFact.ECSVersion(f.FilePath())
+		if version == "" || normalize(version) == normalize(targetVersion) {
+			continue
+		}
+		from, err := ecsIndex.Table(version)
+		if err != nil {
+			continue
+		}
+		current := from.Lookup(f.Name)
+		if current == nil {
+			continue
+		}
+
+		var changes []string
+		if target := to.Lookup(f.Name); target == nil {
+			changes = append(changes, "the field is removed")
+		} else {
+			if current.DataType != target.DataType {
+				changes = append(changes, fmt.Sprintf("the type changes from %s to %s", current.DataType, target.DataType))
+			}
+			if !from.IsArray(f.Name) && to.IsArray(f.Name) {
+				changes = append(changes, "the field gains array normalization")
+			}
+			if current.Description != target.Description {
+				changes = append(changes, "the description changes")
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		pos, end := analysis.KeyRange(pass, f, "name")
+		pass.Report(analysis.Diagnostic{
+			Pos:      pos,
+			End:      end,
+			Category: pass.Analyzer.Name,
+			Field:    f.Name,
+			Message: fmt.Sprintf("upgrading ECS from %s to %s affects %s: %s",
+				normalize(version), normalize(targetVersion), f.Name, strings.Join(changes, "; ")),
+		})
+	}
+
+	return nil, nil
+}
+
+func normalize(version string) string {
+	return strings.TrimPrefix(version, "v")
+}
@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ecsupgrade
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
+	"github.com/andrewkroh/fydler/internal/fydler"
+)
+
+func Test(t *testing.T) {
+	const path = "testdata/my_package/data_stream/foo/fields/fields.yml"
+
+	require.NoError(t, ecsindexfact.Analyzer.Flags.Set("schema", "testdata/schemas"))
+	t.Cleanup(func() { _ = ecsindexfact.Analyzer.Flags.Set("schema", "") })
+
+	diag := func(line, endCol int, field, msg string) analysis.Diagnostic {
+		return analysis.Diagnostic{
+			Pos:      analysis.Pos{File: path, Line: line, Col: 3},
+			End:      analysis.Pos{File: path, Line: line, Col: endCol},
+			Category: "ecsupgrade",
+			Field:    field,
+			Message:  msg,
+		}
+	}
+
+	testCases := []struct {
+		Name  string
+		To    string
+		Diags []analysis.Diagnostic
+	}{
+		{
+			Name: "disabled",
+		},
+		{
+			Name: "same_version",
+			To:   "v1.0.0",
+		},
+		{
+			Name: "upgrade",
+			To:   "2.0.0",
+			Diags: []analysis.Diagnostic{
+				diag(4, 18, "user.name", "upgrading ECS from 1.0.0 to 2.0.0 affects user.name: the type changes from keyword to wildcard"),
+				diag(6, 19, "related.ip", "upgrading ECS from 1.0.0 to 2.0.0 affects related.ip: the field gains array normalization"),
+				diag(8, 16, "message", "upgrading ECS from 1.0.0 to 2.0.0 affects message: the description changes"),
+				diag(10, 19, "error.code", "upgrading ECS from 1.0.0 to 2.0.0 affects error.code: the field is removed"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			targetVersion = tc.To
+			t.Cleanup(func() { targetVersion = "" })
+
+			_, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer}, path)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.Diags, diags)
+		})
+	}
+
+	t.Run("unknown_target", func(t *testing.T) {
+		targetVersion = "1.2.3"
+		t.Cleanup(func() { targetVersion = "" })
+
+		_, _, err := fydler.Run([]*analysis.Analyzer{Analyzer}, path)
+		assert.ErrorContains(t, err, `failed to load target ECS version "1.2.3"`)
+	})
+}
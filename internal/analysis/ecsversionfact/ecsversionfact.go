@@ -17,20 +17,15 @@
 
 // Package ecsversionfact provides a fact that returns the ECS version
 // associated with a fields.yml file. The ECS version is determined
-// by looking for a build.yml file for the package containing the fields.
+// from the build.yml file of the package containing the fields.
 package ecsversionfact
 
 import (
-	"errors"
 	"fmt"
-	"io/fs"
-	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
-	"github.com/andrewkroh/go-package-spec/pkgspec"
-	"gopkg.in/yaml.v3"
-
 	"github.com/andrewkroh/fydler/internal/analysis"
 )
 
@@ -38,7 +33,8 @@ var Analyzer = &analysis.Analyzer{
 	Name: "ecsversionfact",
 	Description: "Gathers the ECS version associated with fields. " +
 		"It reports a diagnostic if the ECS version has not been specified.",
-	Run: run,
+	Run:        run,
+	ResultType: reflect.TypeFor[*Fact](),
 }
 
 type Fact struct {
@@ -68,20 +64,22 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			continue
 		}
 
-		ecsRef, err := lookupECSReference(dir)
-		if err != nil {
-			if errors.Is(err, fs.ErrNotExist) {
-				pass.Report(analysis.Diagnostic{
-					Pos:      analysis.NewPos(f.FileMetadata),
-					Category: pass.Analyzer.Name,
-					Message:  "missing ecs version reference because build.yml not found",
-				})
-				notExist[dir] = struct{}{}
-				continue
-			}
-			return nil, fmt.Errorf("failed to read ecs version: %w", err)
+		owner, found := pass.Packages.Owner(f.FilePath())
+		if found && owner.Package.BuildManifestErr != nil {
+			return nil, fmt.Errorf("failed to read ecs version: %w", owner.Package.BuildManifestErr)
+		}
+		if !found || owner.Package.BuildManifest == nil {
+			pass.Report(analysis.Diagnostic{
+				Pos:      analysis.NewPos(f.FileMetadata),
+				Category: pass.Analyzer.Name,
+				Message:  "missing ecs version reference because build.yml not found",
+			})
+			notExist[dir] = struct{}{}
+			continue
 		}
 
+		// Strip prefix from git@v1.2.3.
+		ecsRef := strings.TrimPrefix(owner.Package.BuildManifest.Dependencies.ECS.Reference, "git@")
 		if ecsRef == "" {
 			notExist[dir] = struct{}{}
 			pass.Report(analysis.Diagnostic{
@@ -97,49 +95,3 @@ func run(pass *analysis.Pass) (interface{}, error) {
 
 	return &Fact{dirToECSVersion: dirToECSVersion}, nil
 }
-
-func lookupECSReference(dir string) (string, error) {
-	f, err := openBuildManifest(dir)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-
-	var manifest pkgspec.BuildManifest
-	dec := yaml.NewDecoder(f)
-	if err = dec.Decode(&manifest); err != nil {
-		return "", fmt.Errorf("failed to unmarshal %s: %w", f.Name(), err)
-	}
-
-	// Strip prefix from git@v1.2.3.
-	gitRef := manifest.Dependencies.ECS.Reference
-	gitRef = strings.TrimPrefix(gitRef, "git@")
-	return gitRef, nil
-}
-
-// searchPaths contains relative paths from a fields.yml file to
-// a package build.yml file.
-var searchPaths = []string{
-	// Integration data stream fields.
-	"../../../_dev/build/build.yml",
-	// Input package fields.
-	"../_dev/build/build.yml",
-	// Transform fields.
-	"../../../../_dev/build/build.yml",
-}
-
-func openBuildManifest(dir string) (*os.File, error) {
-	for _, searchPath := range searchPaths {
-		f, err := os.Open(filepath.Join(dir, searchPath))
-		if err != nil {
-			if errors.Is(err, fs.ErrNotExist) {
-				continue
-			}
-			return nil, err
-		}
-
-		return f, nil
-	}
-
-	return nil, fs.ErrNotExist
-}
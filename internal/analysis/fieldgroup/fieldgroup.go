@@ -25,6 +25,7 @@ import (
 
 	"github.com/andrewkroh/go-package-spec/pkgspec"
 	"github.com/goccy/go-yaml"
+	yamlast "github.com/goccy/go-yaml/ast"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
 	"github.com/andrewkroh/fydler/internal/yamledit"
@@ -56,10 +57,19 @@ func run(pass *analysis.Pass) (interface{}, error) {
 				}
 			}
 
+			suggestions, err := analysis.SuggestEdit(pass, f, "Use 'type: group'", groupTypeEdit(f))
+			if err != nil {
+				return err
+			}
+
+			pos, end := analysis.KeyRange(pass, f, "type")
 			pass.Report(analysis.Diagnostic{
-				Pos:      analysis.NewPos(f.FileMetadata),
-				Category: pass.Analyzer.Name,
-				Message:  fmt.Sprintf("%s contains 'fields' and must be declared as 'type: group'", f.Name),
+				Pos:            pos,
+				End:            end,
+				Category:       pass.Analyzer.Name,
+				Field:          f.Name,
+				Message:        fmt.Sprintf("%s contains 'fields' and must be declared as 'type: group'", f.Name),
+				SuggestedFixes: suggestions,
 			})
 		}
 		return nil
@@ -70,16 +80,22 @@ func run(pass *analysis.Pass) (interface{}, error) {
 func fixGroupType(field *pkgspec.Field, pass *analysis.Pass) (fixed bool, err error) {
 	ast := pass.AST[field.FilePath()]
 
-	p, err := yaml.PathString(analysis.YAMLPath(field) + ".type")
-	if err != nil {
-		return false, err
-	}
-
-	err = yamledit.SetString(ast.File, p, "group")
-	if err != nil {
+	if err = groupTypeEdit(field)(ast.File); err != nil {
 		return false, err
 	}
 
 	ast.Modified = true
 	return true, nil
 }
+
+// groupTypeEdit returns an edit that sets the field's type to group.
+func groupTypeEdit(field *pkgspec.Field) analysis.Edit {
+	return func(f *yamlast.File) error {
+		p, err := yaml.PathString(analysis.YAMLPath(field) + ".type")
+		if err != nil {
+			return err
+		}
+
+		return yamledit.SetString(f, p, "group")
+	}
+}
@@ -41,10 +41,19 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			}
 
 			if !fixed {
+				suggestions, err := analysis.SuggestDeleteKey(f, "description", pass)
+				if err != nil {
+					return nil, err
+				}
+
+				pos, end := analysis.KeyRange(pass, f, "description")
 				pass.Report(analysis.Diagnostic{
-					Pos:      analysis.NewPos(f.FileMetadata),
-					Category: pass.Analyzer.Name,
-					Message:  fmt.Sprintf("%s field group contains a 'description', but this is unused by Fleet and can be removed", f.Name),
+					Pos:            pos,
+					End:            end,
+					Category:       pass.Analyzer.Name,
+					Field:          f.Name,
+					Message:        fmt.Sprintf("%s field group contains a 'description', but this is unused by Fleet and can be removed", f.Name),
+					SuggestedFixes: suggestions,
 				})
 			}
 		}
@@ -58,10 +67,19 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			}
 
 			if !fixed {
+				suggestions, err := analysis.SuggestDeleteKey(f, "type", pass)
+				if err != nil {
+					return nil, err
+				}
+
+				pos, end := analysis.KeyRange(pass, f, "type")
 				pass.Report(analysis.Diagnostic{
-					Pos:      analysis.NewPos(f.FileMetadata),
-					Category: pass.Analyzer.Name,
-					Message:  fmt.Sprintf("%s use 'external: %s', therefore 'type' should not be specified", f.Name, f.External),
+					Pos:            pos,
+					End:            end,
+					Category:       pass.Analyzer.Name,
+					Field:          f.Name,
+					Message:        fmt.Sprintf("%s use 'external: %s', therefore 'type' should not be specified", f.Name, f.External),
+					SuggestedFixes: suggestions,
 				})
 			}
 		}
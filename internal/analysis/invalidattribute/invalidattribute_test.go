@@ -36,8 +36,10 @@ func Test(t *testing.T) {
 			Path: "testdata/group_description.yml",
 			Diags: []analysis.Diagnostic{
 				{
-					Pos:      analysis.Pos{File: "testdata/group_description.yml", Line: 2, Col: 3},
+					Pos:      analysis.Pos{File: "testdata/group_description.yml", Line: 3, Col: 3},
+					End:      analysis.Pos{File: "testdata/group_description.yml", Line: 3, Col: 89},
 					Category: "invalidattribute",
+					Field:    "cloud",
 					Message:  "cloud field group contains a 'description', but this is unused by Fleet and can be removed",
 				},
 			},
@@ -46,8 +48,10 @@ func Test(t *testing.T) {
 			Path: "testdata/type_with_external.yml",
 			Diags: []analysis.Diagnostic{
 				{
-					Pos:      analysis.Pos{File: "testdata/type_with_external.yml", Line: 2, Col: 3},
+					Pos:      analysis.Pos{File: "testdata/type_with_external.yml", Line: 4, Col: 3},
+					End:      analysis.Pos{File: "testdata/type_with_external.yml", Line: 4, Col: 24},
 					Category: "invalidattribute",
+					Field:    "message",
 					Message:  "message use 'external: ecs', therefore 'type' should not be specified",
 				},
 			},
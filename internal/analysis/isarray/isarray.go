@@ -21,97 +21,65 @@
 package isarray
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"slices"
-	"sort"
+	"reflect"
 	"strings"
 
-	ecs "github.com/andrewkroh/go-ecs"
+	"github.com/andrewkroh/go-package-spec/pkgspec"
 	"gopkg.in/yaml.v3"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
 	"github.com/andrewkroh/fydler/internal/analysis/ecsversionfact"
+	"github.com/andrewkroh/fydler/internal/jsonwalk"
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
 )
 
 var Analyzer = &analysis.Analyzer{
 	Name: "isarray",
 	Description: "Detects ECS array normalization compliance issues in " +
 		"sample events, pipeline test outputs, and ingest pipelines.",
-	Requires: []*analysis.Analyzer{ecsversionfact.Analyzer},
+	Requires: []*analysis.Analyzer{ecsversionfact.Analyzer, ecsindexfact.Analyzer},
+	Reads:    []reflect.Type{reflect.TypeFor[*ecsversionfact.Fact](), reflect.TypeFor[*ecsindexfact.Fact]()},
 	Run:      run,
 }
 
 func run(pass *analysis.Pass) (any, error) {
-	ecsVersionsFact := pass.ResultOf[ecsversionfact.Analyzer].(*ecsversionfact.Fact)
-
-	// Discover unique data stream roots from field file paths.
-	// The data stream root is the parent of the fields/ directory.
-	type dsInfo struct {
-		root       string
-		ecsVersion string
+	ecsVersionsFact, err := analysis.Result[*ecsversionfact.Fact](pass, ecsversionfact.Analyzer)
+	if err != nil {
+		return nil, err
 	}
-	seen := map[string]struct{}{}
-	var dataStreams []dsInfo
-	for _, f := range pass.Flat {
-		fieldsDir := filepath.Dir(f.FilePath())
-		dsRoot := filepath.Dir(fieldsDir)
-		if _, ok := seen[dsRoot]; ok {
-			continue
-		}
-		seen[dsRoot] = struct{}{}
-		dataStreams = append(dataStreams, dsInfo{
-			root:       dsRoot,
-			ecsVersion: ecsVersionsFact.ECSVersion(f.FilePath()),
-		})
+	ecsIndex, err := analysis.Result[*ecsindexfact.Fact](pass, ecsindexfact.Analyzer)
+	if err != nil {
+		return nil, err
 	}
 
-	// Sort for deterministic output.
-	slices.SortFunc(dataStreams, func(a, b dsInfo) int {
-		return strings.Compare(a.root, b.root)
-	})
-
-	for _, ds := range dataStreams {
-		ecsFields, err := ecs.Fields(ds.ecsVersion)
+	return nil, pass.Packages.ForEachOwner(pass.Context, pass.Flat, func(owner pkgmodel.Owner, fields []*pkgspec.Field) error {
+		ecsFields, err := ecsIndex.Table(ecsVersionsFact.ECSVersion(fields[0].FilePath()))
 		if err != nil {
 			// Fall back to latest ECS version.
-			ecsFields, err = ecs.Fields("")
+			ecsFields, err = ecsIndex.Latest()
 			if err != nil {
-				return nil, fmt.Errorf("failed to load ECS fields: %w", err)
-			}
-		}
-
-		// Build set of ECS fields with array normalization.
-		arrayFields := make(map[string]bool, len(ecsFields))
-		for name, f := range ecsFields {
-			if f.Array {
-				arrayFields[name] = true
+				return fmt.Errorf("failed to load ECS fields: %w", err)
 			}
 		}
 
-		// Allow error.message to be used as an array even though ECS
-		// does not define it with array normalization. Appending to
-		// error.message is a widespread convention in integrations.
-		arrayFields["error.message"] = true
-
-		checkSampleEvent(ds.root, ecsFields, arrayFields, pass)
-		checkPipelineTests(ds.root, ecsFields, arrayFields, pass)
-		checkIngestPipelines(ds.root, ecsFields, arrayFields, pass)
-	}
-
-	return nil, nil
+		checkSampleEvent(owner.Dir(), ecsFields, pass)
+		checkPipelineTests(owner.Dir(), ecsFields, pass)
+		checkIngestPipelines(owner.Dir(), ecsFields, pass)
+		return nil
+	})
 }
 
 // checkSampleEvent checks the sample_event.json for ECS array normalization
 // compliance: fields that should be arrays but aren't, and fields that are
 // arrays but shouldn't be.
-func checkSampleEvent(dsRoot string, ecsFields map[string]*ecs.Field, arrayFields map[string]bool, pass *analysis.Pass) {
-	path := filepath.Join(dsRoot, "sample_event.json")
+func checkSampleEvent(dsRoot string, ecsFields *ecsindexfact.Table, pass *analysis.Pass) {
+	path := jsonwalk.SampleEventFile(dsRoot)
 
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -127,244 +95,77 @@ func checkSampleEvent(dsRoot string, ecsFields map[string]*ecs.Field, arrayField
 	}
 
 	reported := map[string]bool{}
-	lineTable := buildLineTable(data)
-	dec := json.NewDecoder(bytes.NewReader(data))
-
-	// Read opening '{'.
-	t, err := dec.Token()
-	if err != nil || t != json.Delim('{') {
-		return
-	}
-
-	walkJSONObject(dec, "", lineTable, checkArrayNormalization(path, ecsFields, arrayFields, pass, reported))
+	jsonwalk.Document(pass.Context, data, checkArrayNormalization(path, ecsFields, pass, reported))
 }
 
 // checkPipelineTests checks pipeline test expected outputs for ECS array
 // normalization compliance.
-func checkPipelineTests(dsRoot string, ecsFields map[string]*ecs.Field, arrayFields map[string]bool, pass *analysis.Pass) {
-	pattern := filepath.Join(dsRoot, "_dev", "test", "pipeline", "test-*-expected.json")
-	matches, _ := filepath.Glob(pattern)
+func checkPipelineTests(dsRoot string, ecsFields *ecsindexfact.Table, pass *analysis.Pass) {
+	for _, path := range jsonwalk.PipelineTestFiles(dsRoot) {
+		if pass.Context.Err() != nil {
+			return
+		}
 
-	for _, path := range matches {
 		data, err := os.ReadFile(path)
 		if err != nil {
 			continue
 		}
 
 		reported := map[string]bool{}
-		lineTable := buildLineTable(data)
-		dec := json.NewDecoder(bytes.NewReader(data))
-
-		// Read opening '{' of outer object.
-		t, err := dec.Token()
-		if err != nil || t != json.Delim('{') {
-			continue
-		}
-
-		fn := checkArrayNormalization(path, ecsFields, arrayFields, pass, reported)
-
-		// Find the "expected" key and walk each document in its array.
-		for dec.More() {
-			kt, err := dec.Token()
-			if err != nil {
-				break
-			}
-			key, ok := kt.(string)
-			if !ok {
-				break
-			}
-			if key != "expected" {
-				skipOneValue(dec)
-				continue
-			}
-
-			// Read opening '[' of expected array.
-			t, err := dec.Token()
-			if err != nil {
-				break
-			}
-			if t != json.Delim('[') {
-				break
-			}
-
-			// Walk each document in the expected array.
-			for dec.More() {
-				t, err := dec.Token()
-				if err != nil {
-					break
-				}
-				if t != json.Delim('{') {
-					skipAfterToken(dec, t)
-					continue
-				}
-				walkJSONObject(dec, "", lineTable, fn)
-			}
-			break
-		}
+		jsonwalk.PipelineTestExpected(pass.Context, data, checkArrayNormalization(path, ecsFields, pass, reported))
 	}
 }
 
-// checkArrayNormalization returns a walkJSONObject visitor that reports
+// isArrayField returns true if the ECS field may hold an array.
+func isArrayField(ecsFields *ecsindexfact.Table, name string) bool {
+	// Allow error.message to be used as an array even though ECS
+	// does not define it with array normalization. Appending to
+	// error.message is a widespread convention in integrations.
+	return ecsFields.IsArray(name) || name == "error.message"
+}
+
+// checkArrayNormalization returns a jsonwalk.Visitor that reports
 // ECS fields with incorrect array normalization: fields that should be
 // arrays but aren't, and fields that are arrays but shouldn't be.
-func checkArrayNormalization(file string, ecsFields map[string]*ecs.Field, arrayFields map[string]bool, pass *analysis.Pass, reported map[string]bool) func(string, bool, int) {
-	return func(fieldPath string, isArray bool, line int) {
-		if _, isECS := ecsFields[fieldPath]; !isECS {
+func checkArrayNormalization(file string, ecsFields *ecsindexfact.Table, pass *analysis.Pass, reported map[string]bool) jsonwalk.Visitor {
+	return func(fieldPath string, kind jsonwalk.Kind, _ any, span jsonwalk.Span) {
+		isArray := kind == jsonwalk.Array
+		if ecsFields.Lookup(fieldPath) == nil {
 			return
 		}
 		if reported[fieldPath] {
 			return
 		}
 
-		shouldBeArray := arrayFields[fieldPath]
+		shouldBeArray := isArrayField(ecsFields, fieldPath)
 		switch {
 		case shouldBeArray && !isArray:
 			reported[fieldPath] = true
 			pass.Report(analysis.Diagnostic{
-				Pos:      analysis.Pos{File: file, Line: line},
+				Pos:      analysis.Pos{File: file, Line: span.Line, Col: span.Col},
+				End:      analysis.Pos{File: file, Line: span.Line, Col: span.EndCol},
 				Category: pass.Analyzer.Name,
+				Field:    fieldPath,
 				Message:  fmt.Sprintf("ECS field %q is defined as an array, but a scalar value was found", fieldPath),
 			})
 		case !shouldBeArray && isArray:
 			reported[fieldPath] = true
 			pass.Report(analysis.Diagnostic{
-				Pos:      analysis.Pos{File: file, Line: line},
+				Pos:      analysis.Pos{File: file, Line: span.Line, Col: span.Col},
+				End:      analysis.Pos{File: file, Line: span.Line, Col: span.EndCol},
 				Category: pass.Analyzer.Name,
+				Field:    fieldPath,
 				Message:  fmt.Sprintf("ECS field %q is defined as a scalar, but an array value was found", fieldPath),
 			})
 		}
 	}
 }
 
-// walkJSONObject walks a JSON object after '{' has been consumed, calling fn
-// for each key-value pair with the dotted field path, whether the value is a
-// JSON array, and the line number. Keys are visited in sorted order for
-// deterministic output.
-func walkJSONObject(dec *json.Decoder, prefix string, lineTable []int, fn func(path string, isArray bool, line int)) {
-	// Collect all key-value entries first to sort by key.
-	type entry struct {
-		path    string
-		isArray bool
-		line    int
-		isObj   bool
-	}
-	var entries []entry
-
-	for dec.More() {
-		kt, err := dec.Token()
-		if err != nil {
-			break
-		}
-		key, ok := kt.(string)
-		if !ok {
-			break
-		}
-
-		path := key
-		if prefix != "" {
-			path = prefix + "." + key
-		}
-
-		line := offsetToLine(lineTable, int(dec.InputOffset()))
-
-		vt, err := dec.Token()
-		if err != nil {
-			break
-		}
-
-		if d, ok := vt.(json.Delim); ok {
-			switch d {
-			case '{':
-				entries = append(entries, entry{path: path, line: line, isObj: true})
-				walkJSONObject(dec, path, lineTable, fn)
-			case '[':
-				entries = append(entries, entry{path: path, isArray: true, line: line})
-				skipArray(dec)
-			}
-		} else {
-			entries = append(entries, entry{path: path, line: line})
-		}
-	}
-	// Read closing '}'.
-	dec.Token() //nolint:errcheck
-
-	// Sort entries by path for deterministic output.
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].path < entries[j].path
-	})
-	for _, e := range entries {
-		fn(e.path, e.isArray, e.line)
-	}
-}
-
-// --- JSON token helpers ---
-
-func skipOneValue(dec *json.Decoder) {
-	t, err := dec.Token()
-	if err != nil {
-		return
-	}
-	skipAfterToken(dec, t)
-}
-
-func skipAfterToken(dec *json.Decoder, t json.Token) {
-	if d, ok := t.(json.Delim); ok {
-		switch d {
-		case '{':
-			skipObject(dec)
-		case '[':
-			skipArray(dec)
-		}
-	}
-}
-
-func skipObject(dec *json.Decoder) {
-	for dec.More() {
-		dec.Token() //nolint:errcheck // key
-		skipOneValue(dec)
-	}
-	dec.Token() //nolint:errcheck // '}'
-}
-
-func skipArray(dec *json.Decoder) {
-	for dec.More() {
-		skipOneValue(dec)
-	}
-	dec.Token() //nolint:errcheck // ']'
-}
-
-// --- Line number helpers ---
-
-// buildLineTable returns the byte offset of the start of each line (1-indexed).
-func buildLineTable(data []byte) []int {
-	table := []int{0} // line 1 starts at offset 0
-	for i, b := range data {
-		if b == '\n' {
-			table = append(table, i+1)
-		}
-	}
-	return table
-}
-
-// offsetToLine returns the 1-indexed line number for a byte offset.
-func offsetToLine(lineTable []int, offset int) int {
-	lo, hi := 0, len(lineTable)-1
-	for lo < hi {
-		mid := (lo + hi + 1) / 2
-		if lineTable[mid] <= offset {
-			lo = mid
-		} else {
-			hi = mid - 1
-		}
-	}
-	return lo + 1
-}
-
 // --- Ingest pipeline checks (YAML with yaml.Node for line numbers) ---
 
 // checkIngestPipelines checks ingest pipeline YAML files for append
 // processors that target ECS fields without array normalization.
-func checkIngestPipelines(dsRoot string, ecsFields map[string]*ecs.Field, arrayFields map[string]bool, pass *analysis.Pass) {
+func checkIngestPipelines(dsRoot string, ecsFields *ecsindexfact.Table, pass *analysis.Pass) {
 	pattern := filepath.Join(dsRoot, "elasticsearch", "ingest_pipeline", "*.yml")
 	matches, _ := filepath.Glob(pattern)
 
@@ -394,7 +195,7 @@ func checkIngestPipelines(dsRoot string, ecsFields map[string]*ecs.Field, arrayF
 			value := root.Content[i+1]
 
 			if (key.Value == "processors" || key.Value == "on_failure") && value.Kind == yaml.SequenceNode {
-				checkProcessorNodes(path, value, ecsFields, arrayFields, pass, reported)
+				checkProcessorNodes(path, value, ecsFields, pass, reported)
 			}
 		}
 	}
@@ -402,7 +203,7 @@ func checkIngestPipelines(dsRoot string, ecsFields map[string]*ecs.Field, arrayF
 
 // checkProcessorNodes inspects a YAML sequence of processors for append
 // operations targeting non-array ECS fields.
-func checkProcessorNodes(file string, seq *yaml.Node, ecsFields map[string]*ecs.Field, arrayFields map[string]bool, pass *analysis.Pass, reported map[string]bool) {
+func checkProcessorNodes(file string, seq *yaml.Node, ecsFields *ecsindexfact.Table, pass *analysis.Pass, reported map[string]bool) {
 	for _, procNode := range seq.Content {
 		if procNode.Kind != yaml.MappingNode {
 			continue
@@ -417,7 +218,7 @@ func checkProcessorNodes(file string, seq *yaml.Node, ecsFields map[string]*ecs.
 			}
 
 			if procTypeKey.Value == "append" {
-				checkAppendNode(file, procTypeKey, procConfig, ecsFields, arrayFields, pass, reported)
+				checkAppendNode(file, procConfig, ecsFields, pass, reported)
 			}
 
 			// Check foreach processor's inner processor.
@@ -431,7 +232,7 @@ func checkProcessorNodes(file string, seq *yaml.Node, ecsFields map[string]*ecs.
 							Kind:    yaml.SequenceNode,
 							Content: []*yaml.Node{cfgVal},
 						}
-						checkProcessorNodes(file, fakeSeq, ecsFields, arrayFields, pass, reported)
+						checkProcessorNodes(file, fakeSeq, ecsFields, pass, reported)
 					}
 				}
 			}
@@ -442,16 +243,16 @@ func checkProcessorNodes(file string, seq *yaml.Node, ecsFields map[string]*ecs.
 				cfgVal := procConfig.Content[j+1]
 
 				if cfgKey.Value == "on_failure" && cfgVal.Kind == yaml.SequenceNode {
-					checkProcessorNodes(file, cfgVal, ecsFields, arrayFields, pass, reported)
+					checkProcessorNodes(file, cfgVal, ecsFields, pass, reported)
 				}
 			}
 		}
 	}
 }
 
-func checkAppendNode(file string, procTypeKey, config *yaml.Node, ecsFields map[string]*ecs.Field, arrayFields map[string]bool, pass *analysis.Pass, reported map[string]bool) {
+func checkAppendNode(file string, config *yaml.Node, ecsFields *ecsindexfact.Table, pass *analysis.Pass, reported map[string]bool) {
 	var fieldName string
-	var fieldLine int
+	var fieldNode *yaml.Node
 
 	for i := 0; i < len(config.Content)-1; i += 2 {
 		key := config.Content[i]
@@ -459,7 +260,7 @@ func checkAppendNode(file string, procTypeKey, config *yaml.Node, ecsFields map[
 
 		if key.Value == "field" && val.Kind == yaml.ScalarNode {
 			fieldName = val.Value
-			fieldLine = procTypeKey.Line
+			fieldNode = val
 			break
 		}
 	}
@@ -468,10 +269,10 @@ func checkAppendNode(file string, procTypeKey, config *yaml.Node, ecsFields map[
 		return
 	}
 
-	if _, exists := ecsFields[fieldName]; !exists {
+	if ecsFields.Lookup(fieldName) == nil {
 		return // Not an ECS field.
 	}
-	if arrayFields[fieldName] {
+	if isArrayField(ecsFields, fieldName) {
 		return // Field allows array normalization.
 	}
 
@@ -480,9 +281,17 @@ func checkAppendNode(file string, procTypeKey, config *yaml.Node, ecsFields map[
 	}
 	reported[fieldName] = true
 
+	// Point at the field value. Quoted scalars include their quotes.
+	width := len(fieldNode.Value)
+	if fieldNode.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		width += 2
+	}
+
 	pass.Report(analysis.Diagnostic{
-		Pos:      analysis.Pos{File: file, Line: fieldLine},
+		Pos:      analysis.Pos{File: file, Line: fieldNode.Line, Col: fieldNode.Column},
+		End:      analysis.Pos{File: file, Line: fieldNode.Line, Col: fieldNode.Column + width},
 		Category: pass.Analyzer.Name,
+		Field:    fieldName,
 		Message:  fmt.Sprintf("append processor targets ECS field %q which does not have array normalization", fieldName),
 	})
 }
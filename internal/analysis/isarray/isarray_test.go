@@ -18,10 +18,12 @@
 package isarray
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
 	"github.com/andrewkroh/fydler/internal/fydler"
@@ -38,38 +40,52 @@ func Test(t *testing.T) {
 			Path: "testdata/noncompliant/my_package/data_stream/foo/fields/fields.yml",
 			Diags: []analysis.Diagnostic{
 				{
-					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo", "sample_event.json"), Line: 3},
+					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo", "sample_event.json"), Line: 3, Col: 5},
+					End:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo", "sample_event.json"), Line: 3, Col: 15},
 					Category: "isarray",
+					Field:    "event.category",
 					Message:  `ECS field "event.category" is defined as an array, but a scalar value was found`,
 				},
 				{
-					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo", "sample_event.json"), Line: 6},
+					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo", "sample_event.json"), Line: 6, Col: 5},
+					End:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo", "sample_event.json"), Line: 6, Col: 11},
 					Category: "isarray",
+					Field:    "host.name",
 					Message:  `ECS field "host.name" is defined as a scalar, but an array value was found`,
 				},
 				{
-					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo", "sample_event.json"), Line: 9},
+					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo", "sample_event.json"), Line: 9, Col: 5},
+					End:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo", "sample_event.json"), Line: 9, Col: 9},
 					Category: "isarray",
+					Field:    "related.ip",
 					Message:  `ECS field "related.ip" is defined as an array, but a scalar value was found`,
 				},
 				{
-					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/_dev/test/pipeline", "test-sample-expected.json"), Line: 5},
+					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/_dev/test/pipeline", "test-sample-expected.json"), Line: 5, Col: 9},
+					End:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/_dev/test/pipeline", "test-sample-expected.json"), Line: 5, Col: 19},
 					Category: "isarray",
+					Field:    "event.category",
 					Message:  `ECS field "event.category" is defined as an array, but a scalar value was found`,
 				},
 				{
-					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/_dev/test/pipeline", "test-sample-expected.json"), Line: 6},
+					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/_dev/test/pipeline", "test-sample-expected.json"), Line: 6, Col: 9},
+					End:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/_dev/test/pipeline", "test-sample-expected.json"), Line: 6, Col: 15},
 					Category: "isarray",
+					Field:    "event.type",
 					Message:  `ECS field "event.type" is defined as an array, but a scalar value was found`,
 				},
 				{
-					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/_dev/test/pipeline", "test-sample-expected.json"), Line: 8},
+					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/_dev/test/pipeline", "test-sample-expected.json"), Line: 8, Col: 7},
+					End:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/_dev/test/pipeline", "test-sample-expected.json"), Line: 8, Col: 13},
 					Category: "isarray",
+					Field:    "tags",
 					Message:  `ECS field "tags" is defined as an array, but a scalar value was found`,
 				},
 				{
-					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/elasticsearch/ingest_pipeline", "default.yml"), Line: 7},
+					Pos:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/elasticsearch/ingest_pipeline", "default.yml"), Line: 8, Col: 14},
+					End:      analysis.Pos{File: filepath.Join("testdata/noncompliant/my_package/data_stream/foo/elasticsearch/ingest_pipeline", "default.yml"), Line: 8, Col: 23},
 					Category: "isarray",
+					Field:    "host.name",
 					Message:  `append processor targets ECS field "host.name" which does not have array normalization`,
 				},
 			},
@@ -95,3 +111,14 @@ func Test(t *testing.T) {
 		})
 	}
 }
+
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, diags, err := fydler.RunContext(ctx, []*analysis.Analyzer{Analyzer},
+		"testdata/noncompliant/my_package/data_stream/foo/fields/fields.yml")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, results)
+	assert.Empty(t, diags)
+}
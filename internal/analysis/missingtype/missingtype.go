@@ -33,9 +33,12 @@ var Analyzer = &analysis.Analyzer{
 func run(pass *analysis.Pass) (interface{}, error) {
 	for _, f := range pass.Flat {
 		if f.Type == "" && f.External == "" {
+			pos, end := analysis.KeyRange(pass, f, "name")
 			pass.Report(analysis.Diagnostic{
-				Pos:      analysis.NewPos(f.FileMetadata),
+				Pos:      pos,
+				End:      end,
 				Category: pass.Analyzer.Name,
+				Field:    f.Name,
 				Message:  fmt.Sprintf("%s is missing a 'type'", f.Name),
 			})
 		}
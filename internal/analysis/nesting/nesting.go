@@ -21,6 +21,7 @@ package nesting
 import (
 	"cmp"
 	"fmt"
+	"reflect"
 	"slices"
 	"strings"
 
@@ -35,10 +36,14 @@ var Analyzer = &analysis.Analyzer{
 	Description: "Detect fields that are nested below a scalar type field.",
 	Run:         run,
 	Requires:    []*analysis.Analyzer{ecsdefinitionfact.Analyzer},
+	Reads:       []reflect.Type{reflect.TypeFor[*ecsdefinitionfact.Fact]()},
 }
 
 func run(pass *analysis.Pass) (interface{}, error) {
-	ecsDefinitionFact := pass.ResultOf[ecsdefinitionfact.Analyzer].(*ecsdefinitionfact.Fact)
+	ecsDefinitionFact, err := analysis.Result[*ecsdefinitionfact.Fact](pass, ecsdefinitionfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
 
 	// Build map of parent field name to child field.
 	parentChildRelations := map[string][]*pkgspec.Field{}
@@ -65,16 +70,19 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			continue
 		}
 
-		pass.Report(makeDiag(f, children))
+		pass.Report(makeDiag(pass, f, children))
 	}
 
 	return nil, nil
 }
 
-func makeDiag(parent *pkgspec.Field, children []*pkgspec.Field) analysis.Diagnostic {
+func makeDiag(pass *analysis.Pass, parent *pkgspec.Field, children []*pkgspec.Field) analysis.Diagnostic {
+	pos, end := analysis.KeyRange(pass, parent, "type")
 	diag := analysis.Diagnostic{
-		Pos:      analysis.NewPos(parent.FileMetadata),
+		Pos:      pos,
+		End:      end,
 		Category: "nesting",
+		Field:    parent.Name,
 		Message:  fmt.Sprintf("%s is defined as a scalar type (%s), but sub-fields were found", parent.Name, string(parent.Type)),
 		Related:  make([]analysis.RelatedInformation, 0, len(children)),
 	}
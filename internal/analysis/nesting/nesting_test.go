@@ -36,8 +36,10 @@ func Test(t *testing.T) {
 			Path: "testdata/nesting.yml",
 			Diags: []analysis.Diagnostic{
 				{
-					Pos:      analysis.Pos{File: "testdata/nesting.yml", Line: 2, Col: 3},
+					Pos:      analysis.Pos{File: "testdata/nesting.yml", Line: 3, Col: 3},
+					End:      analysis.Pos{File: "testdata/nesting.yml", Line: 3, Col: 24},
 					Category: "nesting",
+					Field:    "message",
 					Message:  "message is defined as a scalar type (match_only_text), but sub-fields were found",
 					Related: []analysis.RelatedInformation{
 						{Pos: analysis.Pos{File: "testdata/nesting.yml", Line: 4, Col: 3}, Message: "message.id is sub-field with type keyword"},
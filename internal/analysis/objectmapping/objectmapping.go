@@ -60,9 +60,12 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			return nil
 		}
 
+		pos, end := analysis.KeyRange(pass, f, "type")
 		pass.Report(analysis.Diagnostic{
-			Pos:      analysis.NewPos(f.FileMetadata),
+			Pos:      pos,
+			End:      end,
 			Category: pass.Analyzer.Name,
+			Field:    f.Name,
 			Message:  fmt.Sprintf("%s uses an imprecise mapping, add specific mappings for subfields", f.Name),
 		})
 
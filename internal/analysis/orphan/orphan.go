@@ -0,0 +1,211 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package orphan provides an analyzer that reports declared fields that never
+// appear in a data stream's sample event, pipeline test outputs, or ingest
+// pipelines. It only runs when enabled with the -orphan.enable flag.
+package orphan
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/jsonwalk"
+	"github.com/andrewkroh/fydler/internal/pipelinewalk"
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "orphan",
+	Description: "Report declared non-ECS fields that never appear in the sample event, pipeline " +
+		"test outputs, or ingest pipelines of their data stream (enable with -orphan.enable).",
+	Run: run,
+}
+
+var enabled bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&enabled, "enable", false, "Report orphaned fields. The analyzer does nothing when disabled.")
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if !enabled {
+		return nil, nil
+	}
+
+	return nil, pass.Packages.ForEachOwner(pass.Context, pass.Flat, func(owner pkgmodel.Owner, fields []*pkgspec.Field) error {
+		var candidates []*pkgspec.Field
+		for _, f := range fields {
+			if isCandidate(f) {
+				candidates = append(candidates, f)
+			}
+		}
+		if owner.Transform != nil || len(candidates) == 0 {
+			return nil
+		}
+
+		e := collectEvidence(pass, owner.Dir())
+		if !e.hasDocuments && !e.hasPipelines {
+			// There is nothing to compare the fields against.
+			return nil
+		}
+		confidence := e.confidence()
+
+		for _, f := range candidates {
+			if e.appears(f.Name) {
+				continue
+			}
+
+			pos, end := analysis.KeyRange(pass, f, "name")
+			pass.Report(analysis.Diagnostic{
+				Pos:      pos,
+				End:      end,
+				Category: pass.Analyzer.Name,
+				Field:    f.Name,
+				Message: fmt.Sprintf("%s is declared but never appears in the sample event, pipeline test outputs, "+
+					"or ingest pipelines of %s (%s)", f.Name, owner.Description(), confidence),
+			})
+		}
+		return nil
+	})
+}
+
+// isCandidate returns true for fields that are expected to appear in
+// documents. ECS fields, aliases, and dynamic mappings are excluded.
+func isCandidate(f *pkgspec.Field) bool {
+	return f.External == "" && f.Type != "alias" && !strings.Contains(f.Name, "*")
+}
+
+// evidence is the set of fields that appear in a data stream's documents and
+// ingest pipelines.
+type evidence struct {
+	paths    map[string]struct{} // Field paths that appear.
+	prefixes map[string]struct{} // Objects whose contents are unknown.
+
+	hasDocuments     bool
+	hasPipelineTests bool
+	hasPipelines     bool
+	unknown          map[string]struct{} // Reasons that outputs are unknown.
+}
+
+func collectEvidence(pass *analysis.Pass, dir string) *evidence {
+	e := &evidence{
+		paths:    map[string]struct{}{},
+		prefixes: map[string]struct{}{},
+		unknown:  map[string]struct{}{},
+	}
+
+	if data, err := os.ReadFile(jsonwalk.SampleEventFile(dir)); err == nil {
+		e.hasDocuments = true
+		jsonwalk.Document(pass.Context, data, e.visit)
+	}
+
+	for _, path := range jsonwalk.PipelineTestFiles(dir) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		e.hasDocuments = true
+		e.hasPipelineTests = true
+		jsonwalk.PipelineTestExpected(pass.Context, data, e.visit)
+	}
+
+	for _, path := range pipelinewalk.Files(dir) {
+		if err := e.addPipeline(path); err == nil {
+			e.hasPipelines = true
+		}
+	}
+
+	return e
+}
+
+// visit is a jsonwalk.Visitor that records each field path, including the
+// fields of objects within arrays.
+func (e *evidence) visit(path string, kind jsonwalk.Kind, value any, _ jsonwalk.Span) {
+	e.paths[path] = struct{}{}
+	if kind == jsonwalk.Array {
+		e.addArray(path, value.([]any))
+	}
+}
+
+func (e *evidence) addArray(prefix string, values []any) {
+	for _, v := range values {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				path := prefix + "." + k
+				e.paths[path] = struct{}{}
+				switch child := child.(type) {
+				case map[string]any:
+					e.addArray(path, []any{child})
+				case []any:
+					e.addArray(path, child)
+				}
+			}
+		case []any:
+			e.addArray(prefix, v)
+		}
+	}
+}
+
+// appears returns true if the field, one of its children, or an object
+// with unknown contents containing the field appears.
+func (e *evidence) appears(name string) bool {
+	if _, found := e.paths[name]; found {
+		return true
+	}
+	for p := range e.paths {
+		if strings.HasPrefix(p, name+".") {
+			return true
+		}
+	}
+	for p := range e.prefixes {
+		if name == p || strings.HasPrefix(name, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// confidence describes how likely it is that the reported fields are
+// really orphaned.
+func (e *evidence) confidence() string {
+	var reasons []string
+	if !e.hasDocuments {
+		return "confidence: low; there is no sample event or pipeline test"
+	}
+	if !e.hasPipelineTests {
+		reasons = append(reasons, "there are no pipeline tests")
+	}
+	if len(e.unknown) > 0 {
+		unknown := make([]string, 0, len(e.unknown))
+		for r := range e.unknown {
+			unknown = append(unknown, r)
+		}
+		slices.Sort(unknown)
+		reasons = append(reasons, "the fields written by "+strings.Join(unknown, ", ")+" are unknown")
+	}
+	if len(reasons) == 0 {
+		return "confidence: high"
+	}
+	return "confidence: medium; " + strings.Join(reasons, "; ")
+}
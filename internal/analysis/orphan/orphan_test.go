@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package orphan
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/fydler"
+)
+
+func Test(t *testing.T) {
+	paths, err := filepath.Glob("testdata/my_package/data_stream/*/fields/fields.yml")
+	require.NoError(t, err)
+
+	t.Run("disabled", func(t *testing.T) {
+		_, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer}, paths...)
+		require.NoError(t, err)
+		assert.Empty(t, diags)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		enabled = true
+		t.Cleanup(func() { enabled = false })
+
+		_, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer}, paths...)
+		require.NoError(t, err)
+
+		const ds = "testdata/my_package/data_stream"
+		assert.Equal(t, []analysis.Diagnostic{
+			{
+				Pos:      analysis.Pos{File: filepath.Join(ds, "bar/fields/fields.yml"), Line: 5, Col: 3},
+				End:      analysis.Pos{File: filepath.Join(ds, "bar/fields/fields.yml"), Line: 5, Col: 19},
+				Category: "orphan",
+				Field:    "bar.unused",
+				Message: "bar.unused is declared but never appears in the sample event, pipeline test outputs, " +
+					"or ingest pipelines of data stream bar (confidence: medium; there are no pipeline tests; " +
+					"the fields written by enrich processor, json processor, pipeline processor, templated field names are unknown)",
+			},
+			{
+				Pos:      analysis.Pos{File: filepath.Join(ds, "baz/fields/fields.yml"), Line: 3, Col: 3},
+				End:      analysis.Pos{File: filepath.Join(ds, "baz/fields/fields.yml"), Line: 3, Col: 19},
+				Category: "orphan",
+				Field:    "baz.unused",
+				Message: "baz.unused is declared but never appears in the sample event, pipeline test outputs, " +
+					"or ingest pipelines of data stream baz (confidence: low; there is no sample event or pipeline test)",
+			},
+			{
+				Pos:      analysis.Pos{File: filepath.Join(ds, "foo/fields/fields.yml"), Line: 32, Col: 7},
+				End:      analysis.Pos{File: filepath.Join(ds, "foo/fields/fields.yml"), Line: 32, Col: 19},
+				Category: "orphan",
+				Field:    "foo.unused",
+				Message: "foo.unused is declared but never appears in the sample event, pipeline test outputs, " +
+					"or ingest pipelines of data stream foo (confidence: high)",
+			},
+		}, diags)
+	})
+}
@@ -0,0 +1,235 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package orphan
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/andrewkroh/fydler/internal/pipelinewalk"
+)
+
+// targetDefaults are processors that write their result to target_field,
+// mapped to the target used when target_field is not set.
+var targetDefaults = map[string]string{
+	"community_id":      "network.community_id",
+	"date":              "@timestamp",
+	"fingerprint":       "fingerprint",
+	"network_direction": "network.direction",
+}
+
+// objectTargetDefaults are processors that write an object of fields to
+// target_field, mapped to the target used when target_field is not set.
+var objectTargetDefaults = map[string]string{
+	"geoip":       "geoip",
+	"ip_location": "geoip",
+	"user_agent":  "user_agent",
+	"uri_parts":   "url",
+}
+
+// inPlaceProcessors are processors that modify field, or write the result to
+// target_field when it is set.
+var inPlaceProcessors = map[string]struct{}{
+	"bytes":        {},
+	"convert":      {},
+	"dot_expander": {},
+	"gsub":         {},
+	"html_strip":   {},
+	"join":         {},
+	"lowercase":    {},
+	"sort":         {},
+	"split":        {},
+	"trim":         {},
+	"uppercase":    {},
+	"urldecode":    {},
+}
+
+// passiveProcessors are processors that do not write fields.
+var passiveProcessors = map[string]struct{}{
+	"drop":      {},
+	"fail":      {},
+	"remove":    {},
+	"reroute":   {},
+	"terminate": {},
+}
+
+var (
+	// grokField matches the field name of a grok pattern such as
+	// %{IP:source.ip} or %{NUMBER:foo.count:int}.
+	grokField = regexp.MustCompile(`%\{[A-Za-z0-9_]+:([^:}]+)(?::[a-z]+)?\}`)
+
+	// grokNamedGroup matches the field name of a named capture group such as
+	// (?<foo.bar>\w+).
+	grokNamedGroup = regexp.MustCompile(`\(\?<([^>]+)>`)
+
+	// dissectKey matches a key of a dissect pattern such as %{foo.bar},
+	// %{+foo.bar/2}, or %{foo.bar->}.
+	dissectKey = regexp.MustCompile(`%\{([^}]*)\}`)
+
+	// ingestPipelineRef matches a reference to another pipeline of the same
+	// data stream such as {{ IngestPipeline "foo" }}.
+	ingestPipelineRef = regexp.MustCompile(`^\{\{\s*IngestPipeline\s+"([^"]+)"\s*\}\}$`)
+)
+
+// addPipeline records the fields written by the processors of an ingest
+// pipeline.
+func (e *evidence) addPipeline(path string) error {
+	return pipelinewalk.File(path, func(typ, config *yaml.Node) {
+		e.addProcessor(filepath.Dir(path), typ.Value, config)
+	})
+}
+
+// addProcessor records the fields written by a processor. Processors whose
+// output is not modeled are recorded as unknown so that they lower the
+// confidence of the results.
+func (e *evidence) addProcessor(pipelineDir, typ string, config *yaml.Node) {
+	switch typ {
+	case "set", "append":
+		e.addField(stringValue(config, "field"))
+	case "rename":
+		e.addField(stringValue(config, "target_field"))
+	case "grok":
+		var patterns []string
+		if list := pipelinewalk.MappingValue(config, "patterns"); list != nil && list.Kind == yaml.SequenceNode {
+			for _, p := range list.Content {
+				patterns = append(patterns, p.Value)
+			}
+		}
+		if defs := pipelinewalk.MappingValue(config, "pattern_definitions"); defs != nil && defs.Kind == yaml.MappingNode {
+			for i := 1; i < len(defs.Content); i += 2 {
+				patterns = append(patterns, defs.Content[i].Value)
+			}
+		}
+		for _, p := range patterns {
+			for _, m := range grokField.FindAllStringSubmatch(p, -1) {
+				e.addField(m[1])
+			}
+			for _, m := range grokNamedGroup.FindAllStringSubmatch(p, -1) {
+				e.addField(m[1])
+			}
+		}
+	case "dissect":
+		e.addDissectPattern(stringValue(config, "pattern"))
+	case "csv":
+		if list := pipelinewalk.MappingValue(config, "target_fields"); list != nil && list.Kind == yaml.SequenceNode {
+			for _, f := range list.Content {
+				e.addField(f.Value)
+			}
+		}
+	case "json", "kv":
+		// The contents of the target object are unknown, but the fields
+		// within it can be assumed to appear.
+		e.unknown[typ+" processor"] = struct{}{}
+		if target := stringValue(config, "target_field"); target != "" {
+			e.prefixes[target] = struct{}{}
+		}
+	case "registered_domain":
+		prefix := stringValue(config, "target_field")
+		if prefix != "" {
+			prefix += "."
+		}
+		for _, name := range []string{"domain", "registered_domain", "top_level_domain", "subdomain"} {
+			e.addField(prefix + name)
+		}
+	case "pipeline":
+		// Pipelines of the same data stream are read on their own.
+		m := ingestPipelineRef.FindStringSubmatch(stringValue(config, "name"))
+		if m == nil || !hasPipelineFile(pipelineDir, m[1]) {
+			e.unknown[typ+" processor"] = struct{}{}
+		}
+	default:
+		if target, found := targetDefaults[typ]; found {
+			if s := stringValue(config, "target_field"); s != "" {
+				target = s
+			}
+			e.addField(target)
+			return
+		}
+		if target, found := objectTargetDefaults[typ]; found {
+			if s := stringValue(config, "target_field"); s != "" {
+				target = s
+			}
+			e.prefixes[target] = struct{}{}
+			return
+		}
+		if _, found := inPlaceProcessors[typ]; found {
+			if target := stringValue(config, "target_field"); target != "" {
+				e.addField(target)
+			} else {
+				e.addField(stringValue(config, "field"))
+			}
+			return
+		}
+		if _, found := passiveProcessors[typ]; !found {
+			e.unknown[typ+" processor"] = struct{}{}
+		}
+	}
+}
+
+// addDissectPattern records the fields written by a dissect pattern. Skipped
+// keys write nothing, and reference keys take their names from the data.
+func (e *evidence) addDissectPattern(pattern string) {
+	for _, m := range dissectKey.FindAllStringSubmatch(pattern, -1) {
+		key := m[1]
+		key = strings.TrimSuffix(key, "->")
+		if i := strings.LastIndexByte(key, '/'); i >= 0 {
+			key = key[:i]
+		}
+		switch {
+		case key == "", strings.HasPrefix(key, "?"):
+		case strings.HasPrefix(key, "*"), strings.HasPrefix(key, "&"):
+			e.unknown["dissect reference keys"] = struct{}{}
+		default:
+			e.addField(strings.TrimPrefix(key, "+"))
+		}
+	}
+}
+
+// hasPipelineFile returns true if the pipeline directory contains an ingest
+// pipeline with the name.
+func hasPipelineFile(pipelineDir, name string) bool {
+	for _, ext := range []string{".yml", ".json"} {
+		if _, err := os.Stat(filepath.Join(pipelineDir, name+ext)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// addField records a field written by a processor.
+func (e *evidence) addField(name string) {
+	switch {
+	case name == "":
+	case strings.Contains(name, "{{"):
+		e.unknown["templated field names"] = struct{}{}
+	default:
+		e.paths[name] = struct{}{}
+	}
+}
+
+func stringValue(config *yaml.Node, key string) string {
+	v := pipelinewalk.MappingValue(config, key)
+	if v == nil || v.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return v.Value
+}
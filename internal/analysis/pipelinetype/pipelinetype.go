@@ -0,0 +1,232 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package pipelinetype provides an analyzer that checks the processors of a
+// data stream's ingest pipelines against the declared field mappings.
+package pipelinetype
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"gopkg.in/yaml.v3"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/aliasfact"
+	"github.com/andrewkroh/fydler/internal/fieldindex"
+	"github.com/andrewkroh/fydler/internal/pipelinewalk"
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "pipelinetype",
+	Description: "Detects ingest pipeline processors that disagree with the declared field mappings " +
+		"(convert types, date and geoip targets, and set or rename into undeclared fields).",
+	Requires: []*analysis.Analyzer{aliasfact.Analyzer},
+	Reads:    []reflect.Type{reflect.TypeFor[*aliasfact.Fact]()},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	aliasFact, err := analysis.Result[*aliasfact.Fact](pass, aliasfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	// The resolved fields include the types of ECS fields and alias targets.
+	return nil, pass.Packages.ForEachOwner(pass.Context, aliasFact.ResolvedAliases, func(owner pkgmodel.Owner, fields []*pkgspec.Field) error {
+		if owner.Transform != nil {
+			return nil
+		}
+
+		c := &checker{
+			pass:     pass,
+			index:    fieldindex.New(fields),
+			owner:    owner.Description(),
+			complete: pass.Complete(owner),
+		}
+		for _, path := range pipelinewalk.Files(owner.Dir()) {
+			c.file = path
+			// Pipelines that cannot be parsed are not checked.
+			_ = pipelinewalk.File(path, c.checkProcessor)
+		}
+		return nil
+	})
+}
+
+// checker checks the ingest pipelines of a data stream.
+type checker struct {
+	pass  *analysis.Pass
+	index *fieldindex.Index
+	owner string // Description of the data stream for messages.
+	file  string // Pipeline being checked.
+
+	// complete is true when all fields files of the data stream are loaded
+	// so that undeclared fields can be reported.
+	complete bool
+}
+
+// checkProcessor checks a processor against the declared fields.
+func (c *checker) checkProcessor(typeNode, config *yaml.Node) {
+	switch typeNode.Value {
+	case "convert":
+		c.checkConvert(config)
+	case "date":
+		c.checkDate(typeNode, config)
+	case "geoip":
+		c.checkGeoIP(typeNode, config)
+	case "set":
+		c.checkDeclared(typeNode.Value, pipelinewalk.MappingValue(config, "field"))
+	case "rename":
+		c.checkDeclared(typeNode.Value, pipelinewalk.MappingValue(config, "target_field"))
+	}
+}
+
+// convertTypes maps the types of the convert processor to the compatible
+// field types. Integers are also compatible with floating point fields
+// because Elasticsearch accepts them without loss of meaning.
+var convertTypes = map[string][]pkgspec.FieldType{
+	"integer": {"long", "integer", "short", "byte", "unsigned_long", "double", "float", "half_float", "scaled_float"},
+	"long":    {"long", "integer", "short", "byte", "unsigned_long", "double", "float", "half_float", "scaled_float"},
+	"float":   {"double", "float", "half_float", "scaled_float"},
+	"double":  {"double", "float", "half_float", "scaled_float"},
+	"string":  {"keyword", "constant_keyword", "wildcard", "text", "match_only_text", "version", "ip"},
+	"boolean": {"boolean"},
+	"ip":      {"ip", "keyword"},
+}
+
+func (c *checker) checkConvert(config *yaml.Node) {
+	typeNode := pipelinewalk.MappingValue(config, "type")
+	if typeNode == nil {
+		return
+	}
+	compatible, found := convertTypes[typeNode.Value]
+	if !found {
+		// 'auto' or an invalid type.
+		return
+	}
+
+	target := pipelinewalk.MappingValue(config, "target_field")
+	if target == nil {
+		target = pipelinewalk.MappingValue(config, "field")
+	}
+	typ, found := c.lookup(target)
+	if !found || slices.Contains(compatible, typ) {
+		return
+	}
+
+	c.report(typeNode, target.Value, fmt.Sprintf("convert processor converts %s to %s, but the field has type %s",
+		target.Value, typeNode.Value, typ))
+}
+
+func (c *checker) checkDate(typeNode, config *yaml.Node) {
+	name, node := "@timestamp", typeNode
+	if target := pipelinewalk.MappingValue(config, "target_field"); target != nil {
+		name, node = target.Value, target
+	}
+
+	typ, found := c.lookupName(name)
+	if !found || typ == "date" || typ == "date_nanos" {
+		return
+	}
+
+	c.report(node, name, fmt.Sprintf("date processor writes to %s, but the field has type %s", name, typ))
+}
+
+func (c *checker) checkGeoIP(typeNode, config *yaml.Node) {
+	name, node := "geoip", typeNode
+	if target := pipelinewalk.MappingValue(config, "target_field"); target != nil {
+		name, node = target.Value, target
+	}
+	if strings.Contains(name, "{{") {
+		return
+	}
+
+	// Only check the location when the processor writes it.
+	if props := pipelinewalk.MappingValue(config, "properties"); props != nil && props.Kind == yaml.SequenceNode {
+		if !slices.ContainsFunc(props.Content, func(n *yaml.Node) bool { return n.Value == "location" }) {
+			return
+		}
+	}
+
+	if typ, found := c.index.Lookup(name); found && typ != "object" && typ != "group" {
+		c.report(node, name, fmt.Sprintf("geoip processor writes to %s, but the field has type %s instead of object", name, typ))
+		return
+	}
+
+	location := name + ".location"
+	switch typ, found := c.index.Lookup(location); {
+	case !found && c.complete:
+		c.report(node, location, fmt.Sprintf("geoip processor writes to %s, but %s is not declared as geo_point", name, location))
+	case typ != "" && typ != "geo_point":
+		c.report(node, location, fmt.Sprintf("geoip processor writes to %s, but %s has type %s instead of geo_point", name, location, typ))
+	}
+}
+
+// checkDeclared reports a processor that writes to a field that is not
+// declared.
+func (c *checker) checkDeclared(processor string, node *yaml.Node) {
+	if !c.complete || node == nil || node.Kind != yaml.ScalarNode || !isFieldName(node.Value) {
+		return
+	}
+	if c.index.Covered(node.Value) {
+		return
+	}
+
+	c.report(node, node.Value, fmt.Sprintf("%s processor writes to %s, but the field is not declared in the fields of %s",
+		processor, node.Value, c.owner))
+}
+
+// lookup returns the declared type of the field named by the node. It
+// returns false if the field is not declared or its type is unknown.
+func (c *checker) lookup(node *yaml.Node) (pkgspec.FieldType, bool) {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	return c.lookupName(node.Value)
+}
+
+func (c *checker) lookupName(name string) (pkgspec.FieldType, bool) {
+	if !isFieldName(name) {
+		return "", false
+	}
+	typ, found := c.index.Lookup(name)
+	if !found || typ == "" || typ == "alias" {
+		return "", false
+	}
+	return typ, true
+}
+
+// isFieldName returns false for templated names and metadata fields such as
+// _index or _ingest.
+func isFieldName(name string) bool {
+	return name != "" && !strings.Contains(name, "{{") && !strings.HasPrefix(name, "_")
+}
+
+func (c *checker) report(node *yaml.Node, field, message string) {
+	pos, end := analysis.NodeRange(c.file, node)
+	c.pass.Report(analysis.Diagnostic{
+		Pos:      pos,
+		End:      end,
+		Category: c.pass.Analyzer.Name,
+		Field:    field,
+		Message:  message,
+	})
+}
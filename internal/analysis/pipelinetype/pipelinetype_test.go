@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipelinetype
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/fydler"
+)
+
+func Test(t *testing.T) {
+	const foo = "testdata/my_package/data_stream/foo"
+	pipeline := filepath.Join(foo, "elasticsearch/ingest_pipeline", "default.yml")
+
+	testCases := []struct {
+		Name  string
+		Paths []string
+		Diags []analysis.Diagnostic
+	}{
+		{
+			Name: "foo",
+			Paths: []string{
+				filepath.Join(foo, "fields/base-fields.yml"),
+				filepath.Join(foo, "fields/ecs.yml"),
+				filepath.Join(foo, "fields/fields.yml"),
+			},
+			Diags: []analysis.Diagnostic{
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 6, Col: 13},
+					End:      analysis.Pos{File: pipeline, Line: 6, Col: 19},
+					Category: "pipelinetype",
+					Field:    "foo.count",
+					Message:  "convert processor converts foo.count to string, but the field has type long",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 16, Col: 21},
+					End:      analysis.Pos{File: pipeline, Line: 16, Col: 27},
+					Category: "pipelinetype",
+					Field:    "foo.ts",
+					Message:  "date processor writes to foo.ts, but the field has type keyword",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 30, Col: 21},
+					End:      analysis.Pos{File: pipeline, Line: 30, Col: 35},
+					Category: "pipelinetype",
+					Field:    "foo.client.geo.location",
+					Message:  "geoip processor writes to foo.client.geo, but foo.client.geo.location is not declared as geo_point",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 33, Col: 21},
+					End:      analysis.Pos{File: pipeline, Line: 33, Col: 30},
+					Category: "pipelinetype",
+					Field:    "foo.count",
+					Message:  "geoip processor writes to foo.count, but the field has type long instead of object",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 45, Col: 14},
+					End:      analysis.Pos{File: pipeline, Line: 45, Col: 27},
+					Category: "pipelinetype",
+					Field:    "foo.unknown",
+					Message:  "set processor writes to foo.unknown, but the field is not declared in the fields of data stream foo",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 52, Col: 25},
+					End:      analysis.Pos{File: pipeline, Line: 52, Col: 36},
+					Category: "pipelinetype",
+					Field:    "foo.missing",
+					Message:  "rename processor writes to foo.missing, but the field is not declared in the fields of data stream foo",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 59, Col: 19},
+					End:      analysis.Pos{File: pipeline, Line: 59, Col: 26},
+					Category: "pipelinetype",
+					Field:    "foo.flag",
+					Message:  "convert processor converts foo.flag to integer, but the field has type boolean",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 68, Col: 14},
+					End:      analysis.Pos{File: pipeline, Line: 68, Col: 24},
+					Category: "pipelinetype",
+					Field:    "event.kind",
+					Message:  "set processor writes to event.kind, but the field is not declared in the fields of data stream foo",
+				},
+			},
+		},
+		{
+			// Only some of the data stream's fields files are loaded so
+			// undeclared fields are not reported.
+			Name: "partial",
+			Paths: []string{
+				filepath.Join(foo, "fields/ecs.yml"),
+				filepath.Join(foo, "fields/fields.yml"),
+			},
+			Diags: []analysis.Diagnostic{
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 6, Col: 13},
+					End:      analysis.Pos{File: pipeline, Line: 6, Col: 19},
+					Category: "pipelinetype",
+					Field:    "foo.count",
+					Message:  "convert processor converts foo.count to string, but the field has type long",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 16, Col: 21},
+					End:      analysis.Pos{File: pipeline, Line: 16, Col: 27},
+					Category: "pipelinetype",
+					Field:    "foo.ts",
+					Message:  "date processor writes to foo.ts, but the field has type keyword",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 33, Col: 21},
+					End:      analysis.Pos{File: pipeline, Line: 33, Col: 30},
+					Category: "pipelinetype",
+					Field:    "foo.count",
+					Message:  "geoip processor writes to foo.count, but the field has type long instead of object",
+				},
+				{
+					Pos:      analysis.Pos{File: pipeline, Line: 59, Col: 19},
+					End:      analysis.Pos{File: pipeline, Line: 59, Col: 26},
+					Category: "pipelinetype",
+					Field:    "foo.flag",
+					Message:  "convert processor converts foo.flag to integer, but the field has type boolean",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer}, tc.Paths...)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.Diags, diags)
+		})
+	}
+}
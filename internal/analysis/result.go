@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package analysis
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Result returns the result of a prerequisite analyzer as type T. It returns
+// an error if the pass's analyzer does not list a in its Requires or T in its
+// Reads, if a does not declare a ResultType of T, or if the result is not a T.
+func Result[T any](pass *Pass, a *Analyzer) (T, error) {
+	var zero T
+
+	if !slices.Contains(pass.Analyzer.Requires, a) {
+		return zero, fmt.Errorf("%s analyzer reads the result of %s, but does not declare it in Requires", pass.Analyzer.Name, a.Name)
+	}
+	want := reflect.TypeFor[T]()
+	if !slices.Contains(pass.Analyzer.Reads, want) {
+		return zero, fmt.Errorf("%s analyzer reads the result of %s as %v, but does not declare it in Reads", pass.Analyzer.Name, a.Name, want)
+	}
+	if a.ResultType != want {
+		return zero, fmt.Errorf("%s analyzer reads the result of %s as %v, but its ResultType is %v", pass.Analyzer.Name, a.Name, want, a.ResultType)
+	}
+
+	v, found := pass.ResultOf[a]
+	if !found {
+		return zero, fmt.Errorf("result of %s analyzer is not available to %s", a.Name, pass.Analyzer.Name)
+	}
+	result, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("result of %s analyzer has type %T, but %v was expected", a.Name, v, reflect.TypeFor[T]())
+	}
+	return result, nil
+}
+
+// ValidateRequires checks the declarations of an analyzer and its
+// prerequisites. Every required analyzer must declare a ResultType because
+// requiring an analyzer is only useful for reading its result, and every fact
+// type in Reads must be the ResultType of a required analyzer.
+func ValidateRequires(a *Analyzer) error {
+	for _, r := range a.Requires {
+		if r == nil {
+			return fmt.Errorf("%s analyzer has a nil entry in Requires", a.Name)
+		}
+		if r.ResultType == nil {
+			return fmt.Errorf("%s analyzer requires %s, but %s does not declare a ResultType", a.Name, r.Name, r.Name)
+		}
+		if err := ValidateRequires(r); err != nil {
+			return err
+		}
+	}
+	for _, t := range a.Reads {
+		provided := slices.ContainsFunc(a.Requires, func(r *Analyzer) bool { return r.ResultType == t })
+		if !provided {
+			return fmt.Errorf("%s analyzer reads %v, but no analyzer in its Requires has that ResultType", a.Name, t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// KeyRange returns the span of an attribute of a field. The span starts at
+// the attribute's key. When the value is a scalar on the same line as the
+// key, the span ends after the value; otherwise it ends after the key. The
+// end is the position immediately after the last character.
+//
+// If the attribute cannot be located in the file, then the span is the
+// field's position with a zero end.
+func KeyRange(pass *Pass, field *pkgspec.Field, key string) (start, end Pos) {
+	start = NewPos(field.FileMetadata)
+
+	f, err := pass.file(field.FilePath())
+	if err != nil {
+		return start, Pos{}
+	}
+
+	p, err := yaml.PathString(YAMLPath(field))
+	if err != nil {
+		return start, Pos{}
+	}
+	n, err := p.FilterFile(f)
+	if err != nil {
+		return start, Pos{}
+	}
+
+	var values []*ast.MappingValueNode
+	switch n := n.(type) {
+	case *ast.MappingNode:
+		values = n.Values
+	case *ast.MappingValueNode:
+		values = []*ast.MappingValueNode{n}
+	}
+
+	for _, kv := range values {
+		kt := kv.Key.GetToken()
+		if kt == nil || kt.Value != key {
+			continue
+		}
+
+		start = tokenPos(field.FilePath(), kt)
+		end = tokenEnd(field.FilePath(), kt)
+
+		if v, ok := kv.Value.(ast.ScalarNode); ok {
+			if _, isLiteral := v.(*ast.LiteralNode); !isLiteral {
+				if vt := v.GetToken(); vt != nil && vt.Position.Line == kt.Position.Line {
+					end = tokenEnd(field.FilePath(), vt)
+				}
+			}
+		}
+		return start, end
+	}
+
+	return start, Pos{}
+}
+
+// PathRange returns the span of the scalar value at a YAML path (e.g.
+// $.dependencies.ecs.reference) in any YAML file. It returns false if the
+// value cannot be located.
+func PathRange(pass *Pass, path, yamlPath string) (start, end Pos, ok bool) {
+	f, err := pass.file(path)
+	if err != nil {
+		return Pos{}, Pos{}, false
+	}
+
+	p, err := yaml.PathString(yamlPath)
+	if err != nil {
+		return Pos{}, Pos{}, false
+	}
+	n, err := p.FilterFile(f)
+	if err != nil {
+		return Pos{}, Pos{}, false
+	}
+
+	v, isScalar := n.(ast.ScalarNode)
+	if !isScalar {
+		return Pos{}, Pos{}, false
+	}
+	t := v.GetToken()
+	if t == nil {
+		return Pos{}, Pos{}, false
+	}
+	return tokenPos(path, t), tokenEnd(path, t), true
+}
+
+// NodeRange returns the span of a scalar node decoded with gopkg.in/yaml.v3,
+// such as a value in an ingest pipeline. Quoted scalars include their quotes.
+func NodeRange(file string, node *yamlv3.Node) (start, end Pos) {
+	width := utf8.RuneCountInString(node.Value)
+	if node.Style&(yamlv3.DoubleQuotedStyle|yamlv3.SingleQuotedStyle) != 0 {
+		width += 2
+	}
+	start = Pos{File: file, Line: node.Line, Col: node.Column}
+	end = Pos{File: file, Line: node.Line, Col: node.Column + width}
+	return start, end
+}
+
+func tokenPos(file string, t *token.Token) Pos {
+	return Pos{File: file, Line: t.Position.Line, Col: t.Position.Column}
+}
+
+// tokenEnd returns the position after the last character of a single line
+// token. The token's original text is used so that quotes are included.
+func tokenEnd(file string, t *token.Token) Pos {
+	text, _, _ := strings.Cut(strings.TrimSpace(t.Origin), "\n")
+	return Pos{File: file, Line: t.Position.Line, Col: t.Position.Column + utf8.RuneCountInString(text)}
+}
+
+// source returns the contents of the file. Contents are cached for the life
+// of the pass.
+func (p *Pass) source(path string) (string, error) {
+	if s, found := p.sources[path]; found {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if p.sources == nil {
+		p.sources = map[string]string{}
+	}
+	p.sources[path] = string(data)
+	return p.sources[path], nil
+}
+
+// CacheSource records the contents of a file that has already been read so
+// that analyzers do not need to read it again. The data must not be modified.
+func (p *Pass) CacheSource(path string, data []byte) {
+	if p.sources == nil {
+		p.sources = map[string]string{}
+	}
+	p.sources[path] = string(data)
+}
+
+// file returns the AST of the file as it was read from disk. The AST is
+// cached for the life of the pass and must not be modified.
+func (p *Pass) file(path string) (*ast.File, error) {
+	if f, found := p.files[path]; found {
+		return f, nil
+	}
+
+	s, err := p.source(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseBytes([]byte(s), parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing %s: %w", path, err)
+	}
+	if p.files == nil {
+		p.files = map[string]*ast.File{}
+	}
+	p.files[path] = f
+	return f, nil
+}
@@ -0,0 +1,316 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package staleecs provides an analyzer that detects packages whose ECS
+// reference in _dev/build/build.yml is too many minor versions behind the
+// latest known version of ECS with the same major version. A newer major
+// version is only mentioned because upgrading to it is a manual migration. It
+// can fix the issue by updating the reference to the latest version of the
+// same major version, but only when no field using 'external: ecs' in the
+// package would change type (or be removed) as a result of the upgrade.
+package staleecs
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"github.com/goccy/go-yaml"
+	yamlast "github.com/goccy/go-yaml/ast"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsversionfact"
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:        "staleecs",
+	Description: "Detect packages whose ECS reference in build.yml is too far behind the latest ECS version of the same major version.",
+	CanFix:      true,
+	Run:         run,
+	Requires:    []*analysis.Analyzer{ecsversionfact.Analyzer, ecsindexfact.Analyzer},
+	Reads:       []reflect.Type{reflect.TypeFor[*ecsversionfact.Fact](), reflect.TypeFor[*ecsindexfact.Fact]()},
+}
+
+var maxMinorBehind int
+
+func init() {
+	Analyzer.Flags.IntVar(&maxMinorBehind, "max-minor-behind", 4, "Maximum number of minor versions that a package's ECS reference may be behind the latest ECS version of the same major version.")
+}
+
+// referencePath is the YAML path of the ECS reference in build.yml.
+const referencePath = "$.dependencies.ecs.reference"
+
+// maxListedChanges is the maximum number of blocking field changes listed in
+// a diagnostic.
+const maxListedChanges = 5
+
+// packageInfo contains the externally referenced ECS fields of a package.
+type packageInfo struct {
+	pkg     *pkgmodel.Package
+	version string
+	fields  []*pkgspec.Field
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	ecsVersionsFact, err := analysis.Result[*ecsversionfact.Fact](pass, ecsversionfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+	ecsIndex, err := analysis.Result[*ecsindexfact.Fact](pass, ecsindexfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := ecsIndex.LatestVersion()
+	if latest == "" {
+		return nil, nil
+	}
+
+	// Group by package in the order of pass.Flat for determinism.
+	var packages []*packageInfo
+	byRoot := map[string]*packageInfo{}
+	for _, f := range pass.Flat {
+		if f.External != "ecs" {
+			continue
+		}
+		owner, found := pass.Packages.Owner(f.FilePath())
+		if !found {
+			continue
+		}
+		// A missing version is reported by ecsversionfact.
+		version := ecsVersionsFact.ECSVersion(f.FilePath())
+		if version == "" {
+			continue
+		}
+
+		info, found := byRoot[owner.Package.Root]
+		if !found {
+			info = &packageInfo{pkg: owner.Package, version: version}
+			byRoot[owner.Package.Root] = info
+			packages = append(packages, info)
+		}
+		info.fields = append(info.fields, f)
+	}
+
+	for _, info := range packages {
+		target := latestInMajor(ecsIndex.Versions(), info.version)
+		if target == "" {
+			continue
+		}
+		behind, stale := staleness(info.version, target)
+		if !stale {
+			continue
+		}
+
+		// An unknown version is reported by ecsdefinitionfact.
+		currentFields, err := ecsIndex.Table(info.version)
+		if err != nil {
+			continue
+		}
+		targetFields, err := ecsIndex.Table(target)
+		if err != nil {
+			return nil, err
+		}
+		changes := typeChanges(info.fields, currentFields, targetFields)
+		fixable := len(changes) == 0
+
+		path := info.pkg.BuildManifestPath()
+		if fixable && pass.Fix {
+			fixed, err := fixReference(pass, path, target)
+			if err != nil {
+				return nil, err
+			}
+			if fixed {
+				continue
+			}
+		}
+
+		var suggestions []analysis.SuggestedFix
+		if fixable {
+			suggestions, err = analysis.SuggestFileEdit(pass, path, "Upgrade ECS to "+target, referenceEdit(target))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var message string
+		if target == latest {
+			message = fmt.Sprintf("ECS reference %s in build.yml is %s the latest ECS version %s", info.version, behind, latest)
+		} else {
+			major, _, _ := parseVersion(target)
+			message = fmt.Sprintf("ECS reference %s in build.yml is %s the latest ECS %d.x version %s", info.version, behind, major, target)
+		}
+		if len(changes) > 0 {
+			if len(changes) > maxListedChanges {
+				changes = append(changes[:maxListedChanges], fmt.Sprintf("and %d more", len(changes)-maxListedChanges))
+			}
+			message += fmt.Sprintf("; it cannot be upgraded automatically because fields would change type: %s", strings.Join(changes, ", "))
+		}
+		if target != latest {
+			message += fmt.Sprintf("; ECS %s is a newer major version that must be adopted manually", latest)
+		}
+
+		pos, end, found := analysis.PathRange(pass, path, referencePath)
+		if !found {
+			pos, end = analysis.Pos{File: path, Line: 1}, analysis.Pos{}
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:            pos,
+			End:            end,
+			Category:       pass.Analyzer.Name,
+			Field:          "dependencies.ecs.reference",
+			Message:        message,
+			SuggestedFixes: suggestions,
+		})
+	}
+
+	return nil, nil
+}
+
+// staleness returns how far behind the target version the current version is
+// (e.g. "6 minor versions behind") and whether that exceeds the allowed number
+// of minor versions. Versions that cannot be parsed or that have different
+// major versions are not considered stale.
+func staleness(current, target string) (string, bool) {
+	curMajor, curMinor, ok := parseVersion(current)
+	if !ok {
+		return "", false
+	}
+	targetMajor, targetMinor, ok := parseVersion(target)
+	if !ok {
+		return "", false
+	}
+
+	if curMajor == targetMajor && targetMinor-curMinor > maxMinorBehind {
+		return fmt.Sprintf("%d minor versions behind", targetMinor-curMinor), true
+	}
+	return "", false
+}
+
+// latestInMajor returns the newest of the versions, which are ordered from
+// oldest to newest, that has the same major version as v. It returns an
+// empty string if there is none.
+func latestInMajor(versions []string, v string) string {
+	major, _, ok := parseVersion(v)
+	if !ok {
+		return ""
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if m, _, ok := parseVersion(versions[i]); ok && m == major {
+			return versions[i]
+		}
+	}
+	return ""
+}
+
+// parseVersion returns the major and minor components of a version like
+// v8.11.0.
+func parseVersion(v string) (major, minor int, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// typeChanges returns a sorted description of the fields whose type differs
+// between the two versions of ECS.
+func typeChanges(fields []*pkgspec.Field, from, to *ecsindexfact.Table) []string {
+	seen := map[string]struct{}{}
+	var changes []string
+	for _, f := range fields {
+		if _, found := seen[f.Name]; found {
+			continue
+		}
+		seen[f.Name] = struct{}{}
+
+		current := from.Lookup(f.Name)
+		if current == nil {
+			continue
+		}
+		switch target := to.Lookup(f.Name); {
+		case target == nil:
+			changes = append(changes, f.Name+" (removed)")
+		case target.DataType != current.DataType:
+			changes = append(changes, fmt.Sprintf("%s (%s to %s)", f.Name, current.DataType, target.DataType))
+		}
+	}
+	slices.Sort(changes)
+	return changes
+}
+
+// fixReference rewrites the ECS reference in build.yml to the given version.
+func fixReference(pass *analysis.Pass, path, version string) (bool, error) {
+	a, err := pass.LoadAST(path)
+	if err != nil {
+		return false, err
+	}
+
+	if !setReference(a.File, version) {
+		return false, nil
+	}
+	a.Modified = true
+	return true, nil
+}
+
+// referenceEdit returns an edit that sets the ECS reference to the given
+// version.
+func referenceEdit(version string) analysis.Edit {
+	return func(f *yamlast.File) error {
+		setReference(f, version)
+		return nil
+	}
+}
+
+// setReference sets the ECS reference in the build.yml AST to the given
+// version. It keeps the reference's git@ and v prefixes. It returns false if
+// the file has no string reference.
+func setReference(f *yamlast.File, version string) bool {
+	p, err := yaml.PathString(referencePath)
+	if err != nil {
+		return false
+	}
+	n, err := p.FilterFile(f)
+	if err != nil {
+		return false
+	}
+	s, ok := n.(*yamlast.StringNode)
+	if !ok {
+		return false
+	}
+
+	ref := strings.TrimPrefix(s.Value, "git@")
+	prefix := s.Value[:len(s.Value)-len(ref)]
+	if strings.HasPrefix(ref, "v") {
+		prefix += "v"
+	}
+	s.Value = prefix + version
+	return true
+}
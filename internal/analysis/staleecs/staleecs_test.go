@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package staleecs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
+	"github.com/andrewkroh/fydler/internal/fydler"
+)
+
+func Test(t *testing.T) {
+	const (
+		fixable = "testdata/fixable/_dev/build/build.yml"
+		blocked = "testdata/blocked/_dev/build/build.yml"
+		major   = "testdata/major/_dev/build/build.yml"
+	)
+
+	// The schemas pin the latest ECS version to 99.10.0, and the latest 98.x
+	// version to 98.15.0, regardless of the versions embedded in go-ecs.
+	require.NoError(t, ecsindexfact.Analyzer.Flags.Set("schema", "testdata/schemas"))
+	t.Cleanup(func() { _ = ecsindexfact.Analyzer.Flags.Set("schema", "") })
+
+	_, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer}, "testdata/*/data_stream/*/fields/*.yml")
+	require.NoError(t, err)
+
+	expected := []analysis.Diagnostic{
+		{
+			Pos:      analysis.Pos{File: blocked, Line: 3, Col: 16},
+			End:      analysis.Pos{File: blocked, Line: 3, Col: 29},
+			Category: "staleecs",
+			Field:    "dependencies.ecs.reference",
+			Message: "ECS reference v99.4.0 in build.yml is 6 minor versions behind the latest ECS version 99.10.0; " +
+				"it cannot be upgraded automatically because fields would change type: user.name (wildcard to keyword)",
+		},
+		{
+			Pos:      analysis.Pos{File: fixable, Line: 3, Col: 16},
+			End:      analysis.Pos{File: fixable, Line: 3, Col: 27},
+			Category: "staleecs",
+			Field:    "dependencies.ecs.reference",
+			Message:  "ECS reference v99.2.0 in build.yml is 8 minor versions behind the latest ECS version 99.10.0",
+		},
+		{
+			Pos:      analysis.Pos{File: major, Line: 3, Col: 16},
+			End:      analysis.Pos{File: major, Line: 3, Col: 27},
+			Category: "staleecs",
+			Field:    "dependencies.ecs.reference",
+			Message: "ECS reference v98.9.0 in build.yml is 6 minor versions behind the latest ECS 98.x version 98.15.0; " +
+				"ECS 99.10.0 is a newer major version that must be adopted manually",
+		},
+	}
+	assert.Equal(t, expected, diags)
+
+	t.Run("max_minor_behind", func(t *testing.T) {
+		maxMinorBehind = 10
+		t.Cleanup(func() { maxMinorBehind = 4 })
+
+		// A package on an older major version is not stale while it is
+		// current within that major version.
+		_, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer}, "testdata/*/data_stream/*/fields/*.yml")
+		require.NoError(t, err)
+		assert.Empty(t, diags)
+	})
+}
+
+func TestFixReference(t *testing.T) {
+	testCases := []struct {
+		In, Out string
+	}{
+		{In: "git@v8.9.0", Out: "git@v8.17.0"},
+		{In: "git@8.9.0", Out: "git@8.17.0"},
+		{In: `"git@v8.9.0"`, Out: `"git@v8.17.0"`},
+		{In: "v8.9.0", Out: "v8.17.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.In, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "build.yml")
+			require.NoError(t, os.WriteFile(path, []byte("# Build settings.\ndependencies:\n  ecs:\n    reference: "+tc.In+"\n"), 0o644))
+
+			pass := &analysis.Pass{Fix: true}
+			fixed, err := fixReference(pass, path, "8.17.0")
+			require.NoError(t, err)
+			require.True(t, fixed)
+
+			a := pass.AST[path]
+			require.NotNil(t, a)
+			assert.True(t, a.Modified)
+			assert.Equal(t, "# Build settings.\ndependencies:\n  ecs:\n    reference: "+tc.Out+"\n", a.File.String())
+
+			fixes, err := analysis.SuggestFileEdit(&analysis.Pass{Suggest: true}, path, "Upgrade", referenceEdit("8.17.0"))
+			require.NoError(t, err)
+			assert.Equal(t, []analysis.SuggestedFix{{
+				Message: "Upgrade",
+				Pos:     analysis.Pos{File: path, Line: 4},
+				End:     analysis.Pos{File: path, Line: 4},
+				NewText: "    reference: " + tc.Out,
+			}}, fixes)
+		})
+	}
+}
+
+func TestStaleness(t *testing.T) {
+	behind, stale := staleness("v8.9.0", "8.17.0")
+	assert.True(t, stale)
+	assert.Equal(t, "8 minor versions behind", behind)
+
+	_, stale = staleness("v8.13.0", "8.17.0")
+	assert.False(t, stale)
+
+	// Major version gaps are not staleness.
+	_, stale = staleness("v8.0.0", "9.2.0")
+	assert.False(t, stale)
+
+	_, stale = staleness("main", "8.17.0")
+	assert.False(t, stale)
+
+	versions := []string{"1.12.2", "8.0.0", "8.17.0", "9.0.0", "9.2.0"}
+	assert.Equal(t, "8.17.0", latestInMajor(versions, "v8.9.0"))
+	assert.Equal(t, "9.2.0", latestInMajor(versions, "9.0.0"))
+	assert.Equal(t, "", latestInMajor(versions, "v7.17.0"))
+	assert.Equal(t, "", latestInMajor(versions, "main"))
+}
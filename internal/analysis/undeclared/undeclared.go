@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package undeclared provides an analyzer that detects fields in sample
+// events and pipeline test outputs that are not declared in the fields of
+// their data stream.
+package undeclared
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/fieldindex"
+	"github.com/andrewkroh/fydler/internal/jsonwalk"
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "undeclared",
+	Description: "Detects fields in sample events and pipeline test outputs " +
+		"that are not declared in the data stream's fields.",
+	Run: run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	return nil, pass.Packages.ForEachOwner(pass.Context, pass.Flat, func(owner pkgmodel.Owner, fields []*pkgspec.Field) error {
+		// Only check complete data streams.
+		if !pass.Complete(owner) {
+			return nil
+		}
+
+		idx := fieldindex.New(fields)
+		checkSampleEvent(owner.Dir(), idx, owner.Description(), pass)
+		checkPipelineTests(owner.Dir(), idx, owner.Description(), pass)
+		return nil
+	})
+}
+
+// checkSampleEvent reports the undeclared fields in sample_event.json.
+func checkSampleEvent(dir string, idx *fieldindex.Index, desc string, pass *analysis.Pass) {
+	path := jsonwalk.SampleEventFile(dir)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:      analysis.Pos{File: path},
+			Category: pass.Analyzer.Name,
+			Message:  fmt.Sprintf("failed to read sample event: %v", err),
+		})
+		return
+	}
+
+	reported := map[string]bool{}
+	jsonwalk.Document(pass.Context, data, reportUndeclared(path, idx, desc, pass, reported))
+}
+
+// checkPipelineTests reports the undeclared fields in the expected outputs
+// of the pipeline tests.
+func checkPipelineTests(dir string, idx *fieldindex.Index, desc string, pass *analysis.Pass) {
+	for _, path := range jsonwalk.PipelineTestFiles(dir) {
+		if pass.Context.Err() != nil {
+			return
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		reported := map[string]bool{}
+		jsonwalk.PipelineTestExpected(pass.Context, data, reportUndeclared(path, idx, desc, pass, reported))
+	}
+}
+
+// reportUndeclared returns a jsonwalk.Visitor that reports each leaf field
+// that is not declared. Each field is reported once per file.
+func reportUndeclared(file string, idx *fieldindex.Index, desc string, pass *analysis.Pass, reported map[string]bool) jsonwalk.Visitor {
+	return func(fieldPath string, kind jsonwalk.Kind, _ any, span jsonwalk.Span) {
+		if kind == jsonwalk.Object || reported[fieldPath] || declared(idx, fieldPath, kind) {
+			return
+		}
+
+		reported[fieldPath] = true
+		pass.Report(analysis.Diagnostic{
+			Pos:      analysis.Pos{File: file, Line: span.Line, Col: span.Col},
+			End:      analysis.Pos{File: file, Line: span.Line, Col: span.EndCol},
+			Category: pass.Analyzer.Name,
+			Field:    fieldPath,
+			Message:  fmt.Sprintf("%s is not declared in the fields of %s", fieldPath, desc),
+		})
+	}
+}
+
+// declared returns true if the field is declared or if it is within the
+// value of a declared field, such as an object, flattened, or geo_point
+// field. An array is also declared when fields are declared beneath it,
+// as with an array of objects.
+func declared(idx *fieldindex.Index, name string, kind jsonwalk.Kind) bool {
+	return idx.Covered(name) || (kind == jsonwalk.Array && idx.HasChildren(name))
+}
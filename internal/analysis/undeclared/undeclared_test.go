@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package undeclared
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/fydler"
+)
+
+func Test(t *testing.T) {
+	const foo = "testdata/my_package/data_stream/foo"
+	sampleEvent := filepath.Join(foo, "sample_event.json")
+	pipelineTest := filepath.Join(foo, "_dev/test/pipeline", "test-sample.log-expected.json")
+
+	testCases := []struct {
+		Name  string
+		Paths []string
+		Diags []analysis.Diagnostic
+	}{
+		{
+			Name: "foo",
+			Paths: []string{
+				filepath.Join(foo, "fields/base-fields.yml"),
+				filepath.Join(foo, "fields/ecs.yml"),
+				filepath.Join(foo, "fields/fields.yml"),
+			},
+			Diags: []analysis.Diagnostic{
+				{
+					Pos:      analysis.Pos{File: sampleEvent, Line: 10, Col: 5},
+					End:      analysis.Pos{File: sampleEvent, Line: 10, Col: 15},
+					Category: "undeclared",
+					Field:    "event.original",
+					Message:  "event.original is not declared in the fields of data stream foo",
+				},
+				{
+					Pos:      analysis.Pos{File: sampleEvent, Line: 18, Col: 5},
+					End:      analysis.Pos{File: sampleEvent, Line: 18, Col: 14},
+					Category: "undeclared",
+					Field:    "foo.unknown",
+					Message:  "foo.unknown is not declared in the fields of data stream foo",
+				},
+				{
+					Pos:      analysis.Pos{File: pipelineTest, Line: 6, Col: 9},
+					End:      analysis.Pos{File: pipelineTest, Line: 6, Col: 18},
+					Category: "undeclared",
+					Field:    "foo.unknown",
+					Message:  "foo.unknown is not declared in the fields of data stream foo",
+				},
+				{
+					Pos:      analysis.Pos{File: pipelineTest, Line: 13, Col: 7},
+					End:      analysis.Pos{File: pipelineTest, Line: 13, Col: 13},
+					Category: "undeclared",
+					Field:    "tags",
+					Message:  "tags is not declared in the fields of data stream foo",
+				},
+			},
+		},
+		{
+			// Only some of the data stream's fields files are loaded.
+			Name:  "bar",
+			Paths: []string{"testdata/my_package/data_stream/bar/fields/fields.yml"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer}, tc.Paths...)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.Diags, diags)
+		})
+	}
+}
@@ -50,10 +50,21 @@ func run(pass *analysis.Pass) (interface{}, error) {
 				continue
 			}
 
+			var suggestions []analysis.SuggestedFix
+			if safeToRemove[attrName] {
+				if suggestions, err = analysis.SuggestDeleteKey(f, attrName, pass); err != nil {
+					return err
+				}
+			}
+
+			pos, end := analysis.KeyRange(pass, f, attrName)
 			pass.Report(analysis.Diagnostic{
-				Pos:      analysis.NewPos(f.FileMetadata),
-				Category: pass.Analyzer.Name,
-				Message:  fmt.Sprintf("%s contains an unknown attribute %q", f.Name, attrName),
+				Pos:            pos,
+				End:            end,
+				Category:       pass.Analyzer.Name,
+				Field:          f.Name,
+				Message:        fmt.Sprintf("%s contains an unknown attribute %q", f.Name, attrName),
+				SuggestedFixes: suggestions,
 			})
 		}
 		return nil
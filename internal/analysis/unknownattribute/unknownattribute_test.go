@@ -36,8 +36,10 @@ func Test(t *testing.T) {
 			Path: "testdata/fields.yml",
 			Diags: []analysis.Diagnostic{
 				{
-					Pos:      analysis.Pos{File: "testdata/fields.yml", Line: 2, Col: 3},
+					Pos:      analysis.Pos{File: "testdata/fields.yml", Line: 3, Col: 3},
+					End:      analysis.Pos{File: "testdata/fields.yml", Line: 3, Col: 24},
 					Category: "unknownattribute",
+					Field:    "message",
 					Message:  `message contains an unknown attribute "typo"`,
 				},
 			},
@@ -48,40 +50,64 @@ func Test(t *testing.T) {
 				{
 					Pos: analysis.Pos{
 						File: string("testdata/group.yml"),
-						Line: int(2),
+						Line: int(6),
 						Col:  int(3),
 					},
+					End: analysis.Pos{
+						File: string("testdata/group.yml"),
+						Line: int(6),
+						Col:  int(44),
+					},
 					Category: string("unknownattribute"),
+					Field:    "cloud",
 					Message:  string("cloud contains an unknown attribute \"footnote\""),
 					Related:  []analysis.RelatedInformation(nil),
 				},
 				{
 					Pos: analysis.Pos{
 						File: string("testdata/group.yml"),
-						Line: int(2),
+						Line: int(4),
 						Col:  int(3),
 					},
+					End: analysis.Pos{
+						File: string("testdata/group.yml"),
+						Line: int(4),
+						Col:  int(11),
+					},
 					Category: string("unknownattribute"),
+					Field:    "cloud",
 					Message:  string("cloud contains an unknown attribute \"group\""),
 					Related:  []analysis.RelatedInformation(nil),
 				},
 				{
 					Pos: analysis.Pos{
 						File: string("testdata/group.yml"),
-						Line: int(2),
+						Line: int(3),
 						Col:  int(3),
 					},
+					End: analysis.Pos{
+						File: string("testdata/group.yml"),
+						Line: int(3),
+						Col:  int(15),
+					},
 					Category: string("unknownattribute"),
+					Field:    "cloud",
 					Message:  string("cloud contains an unknown attribute \"title\""),
 					Related:  []analysis.RelatedInformation(nil),
 				},
 				{
 					Pos: analysis.Pos{
 						File: string("testdata/group.yml"),
-						Line: int(9),
+						Line: int(11),
 						Col:  int(7),
 					},
+					End: analysis.Pos{
+						File: string("testdata/group.yml"),
+						Line: int(11),
+						Col:  int(22),
+					},
 					Category: string("unknownattribute"),
+					Field:    "account.id",
 					Message:  string("account.id contains an unknown attribute \"required\""),
 					Related:  []analysis.RelatedInformation(nil),
 				},
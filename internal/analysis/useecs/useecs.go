@@ -22,8 +22,8 @@
 package useecs
 
 import (
-	"errors"
 	"fmt"
+	"reflect"
 
 	"github.com/andrewkroh/go-ecs"
 	"github.com/andrewkroh/go-package-spec/pkgspec"
@@ -31,6 +31,7 @@ import (
 	yamlast "github.com/goccy/go-yaml/ast"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
 	"github.com/andrewkroh/fydler/internal/yamledit"
 )
 
@@ -39,24 +40,34 @@ var Analyzer = &analysis.Analyzer{
 	Description: "Detect fields that exist in the latest version of ECS, but are not using 'external: ecs'.",
 	CanFix:      true,
 	Run:         run,
+	Requires:    []*analysis.Analyzer{ecsindexfact.Analyzer},
+	Reads:       []reflect.Type{reflect.TypeFor[*ecsindexfact.Fact]()},
 }
 
 func run(pass *analysis.Pass) (interface{}, error) {
+	ecsIndex, err := analysis.Result[*ecsindexfact.Fact](pass, ecsindexfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	ecsFields, err := ecsIndex.Latest()
+	if err != nil {
+		return nil, err
+	}
+
 	for _, f := range pass.Flat {
 		if f.External != "" {
 			continue
 		}
 
-		ecsField, err := ecs.Lookup(f.Name, "")
-		if err != nil {
-			if errors.Is(err, ecs.ErrFieldNotFound) {
-				continue
-			}
-			// Should never happen.
-			return nil, err
+		ecsField := ecsFields.Lookup(f.Name)
+		if ecsField == nil {
+			continue
 		}
 
-		fixed, err := fixWithExternalECS(f, ecsField, pass)
+		edit := externalECSEdit(f, ecsField)
+
+		fixed, err := fixWithExternalECS(f, edit, pass)
 		if err != nil {
 			return nil, err
 		}
@@ -64,93 +75,115 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			continue
 		}
 
+		var suggestions []analysis.SuggestedFix
+		if edit != nil {
+			if suggestions, err = analysis.SuggestEdit(pass, f, "Use 'external: ecs'", edit); err != nil {
+				return nil, err
+			}
+		}
+
 		message := fmt.Sprintf("%s exists in ECS, but the definition is not using 'external: ecs'.", f.Name)
 		if f.Type != "" && ecsField.DataType != string(f.Type) {
 			message += fmt.Sprintf(" The ECS type is %s, but this uses %s", ecsField.DataType, f.Type)
 		}
 
+		pos, end := analysis.KeyRange(pass, f, "name")
 		pass.Report(analysis.Diagnostic{
-			Pos:      analysis.NewPos(f.FileMetadata),
-			Category: pass.Analyzer.Name,
-			Message:  message,
+			Pos:            pos,
+			End:            end,
+			Category:       pass.Analyzer.Name,
+			Field:          f.Name,
+			Message:        message,
+			SuggestedFixes: suggestions,
 		})
 	}
 
 	return nil, nil
 }
 
-// fixWithExternalECS replaces the field node with a new definition that uses
-// 'external: ecs'. It will retain certain attributes that override indexing
-// behavior of the field.
-func fixWithExternalECS(field *pkgspec.Field, ecsField *ecs.Field, pass *analysis.Pass) (fixed bool, err error) {
-	if !pass.Fix {
+// fixWithExternalECS applies the 'external: ecs' edit to the field's file.
+func fixWithExternalECS(field *pkgspec.Field, edit analysis.Edit, pass *analysis.Pass) (fixed bool, err error) {
+	if !pass.Fix || edit == nil {
 		return false, nil
 	}
 
+	ast := pass.AST[field.FilePath()]
+
+	if err = edit(ast.File); err != nil {
+		return false, err
+	}
+
+	ast.Modified = true
+	return true, nil
+}
+
+// externalECSEdit returns an edit that replaces the field node with a new
+// definition that uses 'external: ecs'. It will retain certain attributes that
+// override indexing behavior of the field. It returns nil when the field's
+// type is not compatible with the ECS definition.
+func externalECSEdit(field *pkgspec.Field, ecsField *ecs.Field) analysis.Edit {
 	// An ECS keyword may be replaced with a constant_keyword.
 	// Source: https://github.com/elastic/elastic-package/blob/cafa676c6ec7420e08023f9af98185b114879714/internal/fields/dependency_manager.go#L204
 	overrideWithConstantKeyword := ecsField.DataType == "keyword" && field.Type == "constant_keyword"
 
 	// The type must be the same in order to do the replacement safely.
 	if string(field.Type) != ecsField.DataType && !overrideWithConstantKeyword {
-		return false, nil
-	}
-
-	// Get the old node.
-	yamlPath := analysis.YAMLPath(field)
-	p, err := yaml.PathString(yamlPath)
-	if err != nil {
-		return false, err
+		return nil
 	}
 
-	ast := pass.AST[field.FilePath()]
+	return func(f *yamlast.File) error {
+		// Get the old node.
+		yamlPath := analysis.YAMLPath(field)
+		p, err := yaml.PathString(yamlPath)
+		if err != nil {
+			return err
+		}
 
-	n, err := p.FilterFile(ast.File)
-	if err != nil {
-		return false, fmt.Errorf("failed to get YAML node %q: %w", yamlPath, err)
-	}
+		n, err := p.FilterFile(f)
+		if err != nil {
+			return fmt.Errorf("failed to get YAML node %q: %w", yamlPath, err)
+		}
 
-	// This operates on pass.Flat where the field name is not the original
-	// name from the YAML node. We need the original name to modify the YAML.
-	var o pkgspec.Field
-	if err = yaml.NodeToValue(n, &o); err != nil {
-		return false, fmt.Errorf("failed to read original node: %w", err)
-	}
+		// This operates on pass.Flat where the field name is not the original
+		// name from the YAML node. We need the original name to modify the YAML.
+		var o pkgspec.Field
+		if err = yaml.NodeToValue(n, &o); err != nil {
+			return fmt.Errorf("failed to read original node: %w", err)
+		}
 
-	newField := pkgspec.Field{
-		Name:     o.Name,
-		External: "ecs",
+		newField := pkgspec.Field{
+			Name:     o.Name,
+			External: "ecs",
 
-		// constant_keyword fields should retain their type.
-		Value: o.Value,
+			// constant_keyword fields should retain their type.
+			Value: o.Value,
 
-		// Keep these attributes because they are needed for TSDS.
-		MetricType: o.MetricType,
-		Dimension:  o.Dimension,
+			// Keep these attributes because they are needed for TSDS.
+			MetricType: o.MetricType,
+			Dimension:  o.Dimension,
 
-		// Keep special attributes that control indexing.
-		DocValues: o.DocValues,
-		Index:     o.Index,
-		CopyTo:    o.CopyTo,
-		Enabled:   o.Enabled,
+			// Keep special attributes that control indexing.
+			DocValues: o.DocValues,
+			Index:     o.Index,
+			CopyTo:    o.CopyTo,
+			Enabled:   o.Enabled,
 
-		// Keep the unit type because ECS does not have this concept.
-		Unit: o.Unit,
-	}
-	if overrideWithConstantKeyword {
-		newField.Type = "constant_keyword"
-	}
+			// Keep the unit type because ECS does not have this concept.
+			Unit: o.Unit,
+		}
+		if overrideWithConstantKeyword {
+			newField.Type = "constant_keyword"
+		}
 
-	replacement, err := yaml.ValueToNode(newField)
-	if err != nil {
-		return false, err
-	}
-	yamlast.Walk(yamledit.FieldAttributeOrder, replacement)
+		replacement, err := yaml.ValueToNode(newField)
+		if err != nil {
+			return err
+		}
+		yamlast.Walk(yamledit.FieldAttributeOrder, replacement)
 
-	if err = p.ReplaceWithNode(ast.File, replacement); err != nil {
-		return false, fmt.Errorf("faield to replace node: %w", err)
+		if err = p.ReplaceWithNode(f, replacement); err != nil {
+			return fmt.Errorf("faield to replace node: %w", err)
+		}
+		return nil
 	}
-
-	ast.Modified = true
-	return true, nil
 }
@@ -38,7 +38,9 @@ func Test(t *testing.T) {
 			Diags: []analysis.Diagnostic{
 				{
 					Pos:      analysis.Pos{File: "testdata/fields.yml", Line: 2, Col: 3},
+					End:      analysis.Pos{File: "testdata/fields.yml", Line: 2, Col: 22},
 					Category: "useecs",
+					Field:    "event.dataset",
 					Message:  "event.dataset exists in ECS, but the definition is not using 'external: ecs'. The ECS type is keyword, but this uses constant_keyword",
 				},
 			},
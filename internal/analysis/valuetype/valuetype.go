@@ -0,0 +1,284 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package valuetype provides an analyzer that detects values in sample
+// events and pipeline test outputs that cannot be indexed under the declared
+// type of their field.
+package valuetype
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/aliasfact"
+	"github.com/andrewkroh/fydler/internal/fieldindex"
+	"github.com/andrewkroh/fydler/internal/jsonwalk"
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "valuetype",
+	Description: "Detects values in sample events and pipeline test outputs " +
+		"that cannot be indexed under the declared type of the field.",
+	Requires: []*analysis.Analyzer{aliasfact.Analyzer},
+	Reads:    []reflect.Type{reflect.TypeFor[*aliasfact.Fact]()},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	aliasFact, err := analysis.Result[*aliasfact.Fact](pass, aliasfact.Analyzer)
+	if err != nil {
+		return nil, err
+	}
+
+	// The resolved fields include the types of ECS fields and alias targets.
+	return nil, pass.Packages.ForEachOwner(pass.Context, aliasFact.ResolvedAliases, func(owner pkgmodel.Owner, fields []*pkgspec.Field) error {
+		types := fieldindex.New(fields)
+
+		path := jsonwalk.SampleEventFile(owner.Dir())
+		if data, err := os.ReadFile(path); err == nil {
+			jsonwalk.Document(pass.Context, data, checkValues(path, types, pass, map[string]bool{}))
+		}
+
+		for _, path := range jsonwalk.PipelineTestFiles(owner.Dir()) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			jsonwalk.PipelineTestExpected(pass.Context, data, checkValues(path, types, pass, map[string]bool{}))
+		}
+		return nil
+	})
+}
+
+// checkValues returns a jsonwalk.Visitor that reports values that cannot be
+// indexed under the type of their field. Each field is reported once per
+// file.
+func checkValues(file string, types *fieldindex.Index, pass *analysis.Pass, reported map[string]bool) jsonwalk.Visitor {
+	return func(fieldPath string, kind jsonwalk.Kind, value any, span jsonwalk.Span) {
+		if reported[fieldPath] {
+			return
+		}
+		// An unresolved alias has no type to check against.
+		typ, found := types.Lookup(fieldPath)
+		if !found || typ == "alias" {
+			return
+		}
+
+		var problem string
+		switch kind {
+		case jsonwalk.Object:
+			if isScalarType(typ) {
+				problem = "the value is an object"
+			}
+		case jsonwalk.Array:
+			for _, v := range flatten(value.([]any)) {
+				if problem = checkValue(typ, types.DateFormat(fieldPath), v); problem != "" {
+					break
+				}
+			}
+		default:
+			problem = checkValue(typ, types.DateFormat(fieldPath), value)
+		}
+		if problem == "" {
+			return
+		}
+
+		reported[fieldPath] = true
+		pass.Report(analysis.Diagnostic{
+			Pos:      analysis.Pos{File: file, Line: span.Line, Col: span.Col},
+			End:      analysis.Pos{File: file, Line: span.Line, Col: span.EndCol},
+			Category: pass.Analyzer.Name,
+			Field:    fieldPath,
+			Message:  fmt.Sprintf("%s has type %s, but %s", fieldPath, typ, problem),
+		})
+	}
+}
+
+// flatten returns the elements of an array with nested arrays expanded, the
+// same as how Elasticsearch indexes them.
+func flatten(values []any) []any {
+	var out []any
+	for _, v := range values {
+		if arr, ok := v.([]any); ok {
+			out = append(out, flatten(arr)...)
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// checkValue returns a description of why the value cannot be indexed as the
+// type, or an empty string if it can. Only types with scalar values are
+// checked. The dateFormat is the field's date_format, if any.
+func checkValue(typ pkgspec.FieldType, dateFormat string, v any) string {
+	if v == nil || !isScalarType(typ) {
+		return ""
+	}
+	if _, ok := v.(map[string]any); ok {
+		return "the value is an object"
+	}
+
+	var valid bool
+	switch typ {
+	case "keyword", "constant_keyword", "wildcard", "text", "match_only_text", "version":
+		// Numbers and booleans are coerced to strings.
+		valid = true
+	case "long", "integer", "short", "byte", "unsigned_long":
+		valid = isInteger(typ, v)
+	case "double", "float", "half_float", "scaled_float":
+		valid = isNumber(v)
+	case "boolean":
+		switch v := v.(type) {
+		case bool:
+			valid = true
+		case string:
+			valid = v == "true" || v == "false" || v == ""
+		}
+	case "ip":
+		if s, ok := v.(string); ok {
+			_, err := netip.ParseAddr(s)
+			valid = err == nil
+		}
+	case "date", "date_nanos":
+		valid = isDate(dateFormat, v)
+	}
+	if valid {
+		return ""
+	}
+	return fmt.Sprintf("the value %s is not a valid %s", describe(v), typ)
+}
+
+// isScalarType returns true for the types checked by this analyzer. Other
+// types, such as geo_point or flattened, accept objects.
+func isScalarType(typ pkgspec.FieldType) bool {
+	switch typ {
+	case "keyword", "constant_keyword", "wildcard", "text", "match_only_text", "version",
+		"long", "integer", "short", "byte", "unsigned_long",
+		"double", "float", "half_float", "scaled_float",
+		"boolean", "ip", "date", "date_nanos":
+		return true
+	}
+	return false
+}
+
+// integerRanges are the bounds of the integer types.
+var integerRanges = map[pkgspec.FieldType][2]*big.Int{
+	"byte":          {big.NewInt(-1 << 7), big.NewInt(1<<7 - 1)},
+	"short":         {big.NewInt(-1 << 15), big.NewInt(1<<15 - 1)},
+	"integer":       {big.NewInt(-1 << 31), big.NewInt(1<<31 - 1)},
+	"long":          {big.NewInt(-1 << 63), big.NewInt(1<<63 - 1)},
+	"unsigned_long": {big.NewInt(0), new(big.Int).SetUint64(1<<64 - 1)},
+}
+
+// isInteger returns true if the value is a number, or a string containing a
+// number, that is within the range of the integer type. Fractions are
+// truncated when indexed so they are allowed.
+func isInteger(typ pkgspec.FieldType, v any) bool {
+	s, ok := numberString(v)
+	if !ok {
+		return false
+	}
+	f, _, err := big.ParseFloat(s, 10, 128, big.ToZero)
+	if err != nil || f.IsInf() {
+		return false
+	}
+	i, _ := f.Int(nil)
+	bounds := integerRanges[typ]
+	return i.Cmp(bounds[0]) >= 0 && i.Cmp(bounds[1]) <= 0
+}
+
+// isNumber returns true if the value is a number or a string containing a
+// number.
+func isNumber(v any) bool {
+	s, ok := numberString(v)
+	if !ok {
+		return false
+	}
+	return isFinite(s)
+}
+
+// isFinite returns true if the string is a finite floating point number.
+func isFinite(s string) bool {
+	f, err := strconv.ParseFloat(s, 64)
+	return err == nil && !math.IsInf(f, 0) && !math.IsNaN(f)
+}
+
+func numberString(v any) (string, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.String(), true
+	case string:
+		return strings.TrimSpace(v), true
+	}
+	return "", false
+}
+
+// isoDate matches the strict_date_optional_time format used when a date
+// field does not declare a format.
+var isoDate = regexp.MustCompile(`^[+-]?\d{4,9}(-\d{2}(-\d{2}(T\d{2}(:\d{2}(:\d{2}([.,]\d{1,9})?)?)?(Z|[+-]\d{2}(:?\d{2})?)?)?)?)?$`)
+
+// defaultDateFormat is the format of date fields that do not declare a
+// date_format.
+const defaultDateFormat = "strict_date_optional_time||epoch_millis"
+
+// isDate returns true if the value matches one of the formats of the
+// date_format. A date_format containing a format that is not modeled here,
+// such as a custom pattern, accepts every value because it cannot be checked.
+func isDate(dateFormat string, v any) bool {
+	if dateFormat == "" {
+		dateFormat = defaultDateFormat
+	}
+
+	var valid bool
+	for _, format := range strings.Split(dateFormat, "||") {
+		switch strings.TrimSpace(format) {
+		case "strict_date_optional_time", "date_optional_time",
+			"strict_date_optional_time_nanos", "iso8601":
+			s, ok := v.(string)
+			valid = valid || (ok && isoDate.MatchString(s))
+		case "epoch_millis", "epoch_second":
+			valid = valid || isNumber(v)
+		default:
+			return true
+		}
+	}
+	return valid
+}
+
+func describe(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,166 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package valuetype
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/fydler"
+)
+
+func Test(t *testing.T) {
+	const foo = "testdata/my_package/data_stream/foo"
+	sampleEvent := filepath.Join(foo, "sample_event.json")
+	pipelineTest := filepath.Join(foo, "_dev/test/pipeline", "test-sample.log-expected.json")
+
+	_, diags, err := fydler.Run([]*analysis.Analyzer{Analyzer},
+		filepath.Join(foo, "fields/ecs.yml"),
+		filepath.Join(foo, "fields/fields.yml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []analysis.Diagnostic{
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 3, Col: 5},
+			End:      analysis.Pos{File: sampleEvent, Line: 3, Col: 14},
+			Category: "valuetype",
+			Field:    "event.created",
+			Message:  `event.created has type date, but the value "yesterday" is not a valid date`,
+		},
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 11, Col: 29},
+			End:      analysis.Pos{File: sampleEvent, Line: 11, Col: 34},
+			Category: "valuetype",
+			Field:    "foo.metrics.mem",
+			Message:  `foo.metrics.mem has type long, but the value "high" is not a valid long`,
+		},
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 8, Col: 5},
+			End:      analysis.Pos{File: sampleEvent, Line: 8, Col: 12},
+			Category: "valuetype",
+			Field:    "foo.count",
+			Message:  `foo.count has type long, but the value "many" is not a valid long`,
+		},
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 9, Col: 5},
+			End:      analysis.Pos{File: sampleEvent, Line: 9, Col: 14},
+			Category: "valuetype",
+			Field:    "foo.enabled",
+			Message:  `foo.enabled has type boolean, but the value "yes" is not a valid boolean`,
+		},
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 10, Col: 5},
+			End:      analysis.Pos{File: sampleEvent, Line: 10, Col: 12},
+			Category: "valuetype",
+			Field:    "foo.level",
+			Message:  "foo.level has type byte, but the value 300 is not a valid byte",
+		},
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 12, Col: 5},
+			End:      analysis.Pos{File: sampleEvent, Line: 12, Col: 14},
+			Category: "valuetype",
+			Field:    "foo.peer_ip",
+			Message:  `foo.peer_ip has type ip, but the value "10.0.0.300" is not a valid ip`,
+		},
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 17, Col: 5},
+			End:      analysis.Pos{File: sampleEvent, Line: 17, Col: 9},
+			Category: "valuetype",
+			Field:    "host.ip",
+			Message:  `host.ip has type ip, but the value "bogus" is not a valid ip`,
+		},
+		{
+			Pos:      analysis.Pos{File: sampleEvent, Line: 18, Col: 5},
+			End:      analysis.Pos{File: sampleEvent, Line: 18, Col: 11},
+			Category: "valuetype",
+			Field:    "host.name",
+			Message:  "host.name has type keyword, but the value is an object",
+		},
+		{
+			Pos:      analysis.Pos{File: pipelineTest, Line: 8, Col: 9},
+			End:      analysis.Pos{File: pipelineTest, Line: 8, Col: 15},
+			Category: "valuetype",
+			Field:    "source.port",
+			Message:  `source.port has type long, but the value "http" is not a valid long`,
+		},
+	}, diags)
+}
+
+func TestCheckValue(t *testing.T) {
+	testCases := []struct {
+		Type  pkgspec.FieldType
+		Value any
+		Valid bool
+	}{
+		{"keyword", json.Number("1"), true},
+		{"keyword", true, true},
+		{"long", json.Number("1.9"), true},
+		{"long", "-42", true},
+		{"long", "NaN", false},
+		{"long", true, false},
+		{"unsigned_long", json.Number("18446744073709551615"), true},
+		{"unsigned_long", json.Number("-1"), false},
+		{"double", "1e3", true},
+		{"double", "Infinity", false},
+		{"boolean", "", true},
+		{"boolean", json.Number("1"), false},
+		{"ip", "::1", true},
+		{"ip", json.Number("1"), false},
+		{"date", "2024-01-01", true},
+		{"date", "2024-01-01T00:00:00+02:00", true},
+		{"date", "1704067200000", true},
+		{"date", "01/02/2024", false},
+		{"date", "2024-01-01 00:00:00", false},
+		{"date_nanos", "2024-01-01T00:00:00.123456789Z", true},
+		{"geo_point", map[string]any{"lat": json.Number("1")}, true},
+		{"keyword", map[string]any{}, false},
+		{"long", nil, true},
+	}
+
+	for _, tc := range testCases {
+		problem := checkValue(tc.Type, "", tc.Value)
+		assert.Equal(t, tc.Valid, problem == "", "%s %#v: %s", tc.Type, tc.Value, problem)
+	}
+}
+
+func TestIsDate(t *testing.T) {
+	testCases := []struct {
+		DateFormat string
+		Value      any
+		Valid      bool
+	}{
+		{"", "2024-01-01T00:00:00Z", true},
+		{"", json.Number("1704067200000"), true},
+		{"", "2024-01-01 00:00:00", false},
+		{"epoch_second", json.Number("1704067200"), true},
+		{"epoch_second", "2024-01-01T00:00:00Z", false},
+		{"strict_date_optional_time", "1704067200000", false},
+		{"yyyy-MM-dd HH:mm:ss", "2024-01-01 00:00:00", true},
+		{"strict_date_optional_time||yyyy-MM-dd HH:mm:ss", "yesterday", true},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.Valid, isDate(tc.DateFormat, tc.Value), "%q %#v", tc.DateFormat, tc.Value)
+	}
+}
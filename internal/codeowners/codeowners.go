@@ -0,0 +1,217 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package codeowners parses GitHub CODEOWNERS files and determines the owners
+// of a path using the CODEOWNERS pattern semantics.
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// locations are the paths, relative to the repository root, that are checked
+// for a CODEOWNERS file. The order matches GitHub's precedence.
+var locations = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+// File is a parsed CODEOWNERS file.
+type File struct {
+	Root  string // Repository root directory that patterns are relative to.
+	rules []rule
+}
+
+type rule struct {
+	pattern string
+	owners  []string
+	re      *regexp.Regexp
+}
+
+// Find searches dir and each of its parents for a CODEOWNERS file. It returns
+// false if no file is found.
+func Find(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		for _, loc := range locations {
+			p := filepath.Join(dir, filepath.FromSlash(loc))
+			if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+				return p, true
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Load reads a CODEOWNERS file. The repository root is the directory
+// containing the file, or its parent when the file is in a .github or docs
+// directory.
+func Load(path string) (*File, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	root := filepath.Dir(abs)
+	if base := filepath.Base(root); base == ".github" || base == "docs" {
+		root = filepath.Dir(root)
+	}
+
+	co, err := Parse(f, root)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading %s: %w", path, err)
+	}
+	return co, nil
+}
+
+// Parse parses the CODEOWNERS rules read from r. Patterns are relative to the
+// root directory.
+func Parse(r io.Reader, root string) (*File, error) {
+	co := &File{Root: root}
+
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Remove trailing comments.
+		if idx := strings.Index(line, " #"); idx >= 0 {
+			line = line[:idx]
+		}
+
+		parts := strings.Fields(line)
+		pattern := strings.ReplaceAll(parts[0], `\#`, "#")
+
+		re, err := compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q on line %d: %w", pattern, lineNum, err)
+		}
+
+		co.rules = append(co.rules, rule{
+			pattern: pattern,
+			owners:  parts[1:],
+			re:      re,
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return co, nil
+}
+
+// Owners returns the owners of the path. The last matching rule takes
+// precedence. It returns nil if no rule matches, if the matching rule has no
+// owners, or if the path is outside of the repository root. Relative paths
+// are resolved against the working directory.
+func (co *File) Owners(path string) []string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(co.Root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	for i := len(co.rules) - 1; i >= 0; i-- {
+		if co.rules[i].re.MatchString(rel) {
+			if len(co.rules[i].owners) == 0 {
+				return nil
+			}
+			return co.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// compile converts a CODEOWNERS pattern into a regular expression that
+// matches slash separated paths relative to the repository root.
+//
+// Patterns follow the gitignore rules supported by GitHub. A pattern is
+// anchored to the root when it contains a slash other than a trailing slash;
+// otherwise it matches at any depth. A trailing slash matches only the
+// contents of a directory. A pattern that matches a directory also matches
+// everything beneath it, except when its last segment is a lone asterisk
+// (e.g. docs/*), which matches only the files directly inside the directory.
+func compile(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			// Zero or more directories.
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	switch {
+	case dirOnly:
+		b.WriteString("/.*$")
+	case pattern == "*" && anchored, strings.HasSuffix(pattern, "/*"):
+		b.WriteString("$")
+	default:
+		b.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package codeowners
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{
+			pattern: "*",
+			match:   []string{"README.md", "packages/foo/manifest.yml"},
+		},
+		{
+			pattern: "*.js",
+			match:   []string{"app.js", "src/app.js"},
+			noMatch: []string{"app.jsx"},
+		},
+		{
+			pattern: "/packages/foo",
+			match:   []string{"packages/foo", "packages/foo/data_stream/bar/fields/fields.yml"},
+			noMatch: []string{"packages/foobar/manifest.yml", "x/packages/foo/manifest.yml"},
+		},
+		{
+			pattern: "/packages/foo/",
+			match:   []string{"packages/foo/manifest.yml"},
+			noMatch: []string{"packages/foo"},
+		},
+		{
+			pattern: "apps/",
+			match:   []string{"apps/a.go", "src/apps/a.go"},
+			noMatch: []string{"apps"},
+		},
+		{
+			pattern: "docs/*",
+			match:   []string{"docs/getting-started.md"},
+			noMatch: []string{"docs/build-app/troubleshooting.md"},
+		},
+		{
+			pattern: "/*",
+			match:   []string{"README.md"},
+			noMatch: []string{"packages/foo/manifest.yml"},
+		},
+		{
+			pattern: "**/logs",
+			match:   []string{"logs/a.log", "build/logs/a.log", "deeply/nested/logs/a.log"},
+			noMatch: []string{"build/logsx/a.log"},
+		},
+		{
+			pattern: "/packages/**/fields",
+			match:   []string{"packages/fields/a.yml", "packages/foo/data_stream/bar/fields/fields.yml"},
+			noMatch: []string{"other/foo/fields/a.yml"},
+		},
+		{
+			pattern: "/packages/foo/**",
+			match:   []string{"packages/foo/manifest.yml", "packages/foo/data_stream/bar/fields/fields.yml"},
+			noMatch: []string{"packages/foobar/manifest.yml"},
+		},
+		{
+			pattern: "/packages/fo?",
+			match:   []string{"packages/foo/manifest.yml"},
+			noMatch: []string{"packages/fooo/manifest.yml"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			re, err := compile(tc.pattern)
+			require.NoError(t, err)
+
+			for _, p := range tc.match {
+				assert.True(t, re.MatchString(p), "expected %q to match %s", p, re)
+			}
+			for _, p := range tc.noMatch {
+				assert.False(t, re.MatchString(p), "expected %q not to match %s", p, re)
+			}
+		})
+	}
+}
+
+func TestOwners(t *testing.T) {
+	const codeowners = `
+# Default owner.
+*                       @elastic/ecosystem
+
+/packages/foo           @elastic/foo-team @alice # Trailing comment.
+/packages/foo/kibana
+/packages/bar/          @elastic/bar-team
+`
+
+	root := t.TempDir()
+	co, err := Parse(strings.NewReader(codeowners), root)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		path   string
+		owners []string
+	}{
+		{"README.md", []string{"@elastic/ecosystem"}},
+		{"packages/foo/data_stream/a/fields/fields.yml", []string{"@elastic/foo-team", "@alice"}},
+		{"packages/foo/kibana/dashboard.json", nil},
+		{"packages/bar/fields/fields.yml", []string{"@elastic/bar-team"}},
+		{"../outside/fields.yml", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			assert.Equal(t, tc.owners, co.Owners(filepath.Join(root, filepath.FromSlash(tc.path))))
+		})
+	}
+}
+
+func TestFindAndLoad(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".github"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "packages", "foo", "data_stream"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".github", "CODEOWNERS"),
+		[]byte("/packages/foo @elastic/foo-team\n"), 0o644))
+
+	path, found := Find(filepath.Join(root, "packages", "foo", "data_stream"))
+	require.True(t, found)
+	assert.Equal(t, filepath.Join(root, ".github", "CODEOWNERS"), path)
+
+	co, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, root, co.Root)
+	assert.Equal(t, []string{"@elastic/foo-team"},
+		co.Owners(filepath.Join(root, "packages", "foo", "manifest.yml")))
+}
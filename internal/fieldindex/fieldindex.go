@@ -0,0 +1,139 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package fieldindex provides lookups of the fields declared by a data
+// stream, including the dynamic mappings of wildcard fields.
+package fieldindex
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+)
+
+// Index maps the field names declared by a data stream to their types.
+type Index struct {
+	names     map[string]entry
+	wildcards []wildcard
+}
+
+type entry struct {
+	typ        pkgspec.FieldType
+	dateFormat string
+}
+
+type wildcard struct {
+	re *regexp.Regexp
+	entry
+}
+
+// New returns an index of the fields. When a name is declared more than once
+// the first declaration is used.
+func New(fields []*pkgspec.Field) *Index {
+	idx := &Index{names: map[string]entry{}}
+	for _, f := range fields {
+		dateFormat, _ := f.Extras["date_format"].(string)
+		if !strings.Contains(f.Name, "*") {
+			if _, found := idx.names[f.Name]; !found {
+				idx.names[f.Name] = entry{typ: f.Type, dateFormat: dateFormat}
+			}
+			continue
+		}
+
+		// A dynamic mapping maps values using its object_type.
+		typ := f.Type
+		if typ == "object" && f.ObjectType != "" {
+			typ = pkgspec.FieldType(f.ObjectType)
+		}
+
+		// Like a dynamic template's path_match, '*' matches any characters.
+		parts := strings.Split(f.Name, "*")
+		for i, p := range parts {
+			parts[i] = regexp.QuoteMeta(p)
+		}
+		re := regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+		idx.wildcards = append(idx.wildcards, wildcard{re: re, entry: entry{typ: typ, dateFormat: dateFormat}})
+	}
+	return idx
+}
+
+// Lookup returns the type of the field. It returns false if the field is not
+// declared by name or by a wildcard field. The type is empty when the
+// declaration does not have one (e.g. an 'external: ecs' field that was not
+// enriched).
+func (idx *Index) Lookup(name string) (pkgspec.FieldType, bool) {
+	e, found := idx.lookup(name)
+	return e.typ, found
+}
+
+// DateFormat returns the date_format declared for the field, or an empty
+// string if the field is not declared or does not have one.
+func (idx *Index) DateFormat(name string) string {
+	e, _ := idx.lookup(name)
+	return e.dateFormat
+}
+
+func (idx *Index) lookup(name string) (entry, bool) {
+	if e, found := idx.names[name]; found {
+		return e, true
+	}
+	for _, w := range idx.wildcards {
+		if w.re.MatchString(name) {
+			return w.entry, true
+		}
+	}
+	return entry{}, false
+}
+
+// Covered returns true if the field is declared or one of its parents is
+// declared with a type whose value contains sub-fields (object, flattened,
+// nested, or geo_point). A parent without a type, such as an 'external: ecs'
+// field that was not enriched, is assumed to contain sub-fields.
+func (idx *Index) Covered(name string) bool {
+	if _, found := idx.Lookup(name); found {
+		return true
+	}
+	for path := name; ; {
+		i := strings.LastIndexByte(path, '.')
+		if i < 0 {
+			return false
+		}
+		path = path[:i]
+		if typ, found := idx.Lookup(path); found && (typ == "" || containerTypes[typ]) {
+			return true
+		}
+	}
+}
+
+// containerTypes are the field types whose values contain sub-fields.
+var containerTypes = map[pkgspec.FieldType]bool{
+	"object":    true,
+	"flattened": true,
+	"nested":    true,
+	"geo_point": true,
+}
+
+// HasChildren returns true if a field is declared beneath the name.
+func (idx *Index) HasChildren(name string) bool {
+	for n := range idx.names {
+		if strings.HasPrefix(n, name+".") {
+			return true
+		}
+	}
+	return false
+}
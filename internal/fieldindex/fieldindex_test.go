@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fieldindex
+
+import (
+	"testing"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex(t *testing.T) {
+	idx := New([]*pkgspec.Field{
+		{Name: "foo.count", Type: "long"},
+		{Name: "foo.count", Type: "keyword"},
+		{Name: "foo.attributes", Type: "flattened"},
+		{Name: "foo.items.id", Type: "keyword"},
+		{Name: "foo.labels.*", Type: "object", ObjectType: "keyword"},
+		{Name: "foo.*.bytes", Type: "long"},
+		{Name: "foo.location", Type: "geo_point"},
+		{Name: "foo.raw", Type: "object"},
+		{Name: "foo.seen", Type: "date", Extras: map[string]any{"date_format": "epoch_second"}},
+	})
+
+	typ, found := idx.Lookup("foo.count")
+	assert.True(t, found)
+	assert.EqualValues(t, "long", typ)
+
+	typ, found = idx.Lookup("foo.labels.env")
+	assert.True(t, found)
+	assert.EqualValues(t, "keyword", typ)
+
+	typ, found = idx.Lookup("foo.network.in.bytes")
+	assert.True(t, found)
+	assert.EqualValues(t, "long", typ)
+
+	assert.Equal(t, "epoch_second", idx.DateFormat("foo.seen"))
+	assert.Empty(t, idx.DateFormat("foo.count"))
+
+	_, found = idx.Lookup("foo.attributes.a")
+	assert.False(t, found)
+
+	assert.True(t, idx.Covered("foo.attributes.a.b"))
+	assert.True(t, idx.Covered("foo.labels.env"))
+	assert.True(t, idx.Covered("foo.location.lat"))
+	assert.True(t, idx.Covered("foo.raw.a"))
+	assert.False(t, idx.Covered("foo.count.a"))
+	assert.False(t, idx.Covered("foo.items.id.a"))
+	assert.False(t, idx.Covered("foo.items"))
+	assert.False(t, idx.Covered("bar"))
+
+	assert.True(t, idx.HasChildren("foo.items"))
+	assert.False(t, idx.HasChildren("foo.count"))
+}
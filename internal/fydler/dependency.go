@@ -18,6 +18,8 @@
 package fydler
 
 import (
+	"fmt"
+
 	"golang.org/x/exp/maps"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
@@ -72,8 +74,14 @@ func allEdges(n *analysis.Analyzer) []graph.Edge {
 }
 
 // dependencyOrder returns a list of analyzers ordered such that an analyzer's
-// required analyzers always come before it in the list.
+// required analyzers always come before it in the list. It returns an error
+// if the Requires declarations are invalid (see analysis.ValidateRequires) or
+// if two different analyzers have the same name.
 func dependencyOrder(analyzers []*analysis.Analyzer) ([]*analysis.Analyzer, error) {
+	if err := validateAnalyzers(analyzers); err != nil {
+		return nil, err
+	}
+
 	g := buildGraph(analyzers)
 
 	nodes, err := graph.TopologicalSort(g)
@@ -87,3 +95,35 @@ func dependencyOrder(analyzers []*analysis.Analyzer) ([]*analysis.Analyzer, erro
 	}
 	return orderedAnalyzers, nil
 }
+
+// validateAnalyzers validates the analyzers and all of their prerequisites.
+func validateAnalyzers(analyzers []*analysis.Analyzer) error {
+	byName := map[string]*analysis.Analyzer{}
+	var visit func(a *analysis.Analyzer) error
+	visit = func(a *analysis.Analyzer) error {
+		if other, found := byName[a.Name]; found {
+			if other != a {
+				return fmt.Errorf("multiple analyzers are named %q", a.Name)
+			}
+			return nil
+		}
+		byName[a.Name] = a
+
+		if err := analysis.ValidateRequires(a); err != nil {
+			return err
+		}
+		for _, r := range a.Requires {
+			if err := visit(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, a := range analyzers {
+		if err := visit(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
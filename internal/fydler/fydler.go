@@ -21,32 +21,46 @@ package fydler
 
 import (
 	"cmp"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"reflect"
 	runtimedebug "runtime/debug"
 	"runtime/pprof"
 	"slices"
 	"strings"
 	"text/tabwriter"
+	"time"
 	"unicode"
 
 	"github.com/andrewkroh/go-package-spec/pkgspec"
-	"github.com/goccy/go-yaml/parser"
-	"gopkg.in/yaml.v3"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsindexfact"
+	"github.com/andrewkroh/fydler/internal/pkgmodel"
 	"github.com/andrewkroh/fydler/internal/printer"
 )
 
 var (
 	analyzersFilter  stringListFlag
 	outputTypes      stringListFlag
+	outputs          []output
 	diagnosticFilter stringListFlag
 	fixFindings      bool
+	suggestFixes     bool
+	codeOwnersPath   string
+	ownerFilter      stringListFlag
+	groupByOwner     bool
 	cpuprofile       string
+	summaryTopN      int
+	timeout          time.Duration
+	ecsSchemaPath    string
+	linkOverrides    printer.LinkBuilder
 )
 
 //nolint:revive // This is a pseudo main function so allow exits.
@@ -57,6 +71,11 @@ func Main(analyzers ...*analysis.Analyzer) {
 	log.SetFlags(0)
 	log.SetPrefix(progname + ": ")
 
+	if len(os.Args) > 1 && os.Args[1] == "report-diff" {
+		reportDiffMain(os.Args[2:])
+		return
+	}
+
 	parseFlags(analyzers)
 
 	if cpuprofile != "" {
@@ -75,12 +94,29 @@ func Main(analyzers ...*analysis.Analyzer) {
 		log.Fatal("Must pass a list of fields.yml files (e.g. **/fields/*.yml)")
 	}
 
+	if ecsSchemaPath != "" {
+		// The schema is loaded by the ecsindexfact analyzer.
+		if err := ecsindexfact.Analyzer.Flags.Set("schema", ecsSchemaPath); err != nil {
+			log.Fatalf("Failed to set ECS schema: %v", err)
+		}
+	}
+
 	files := make([]string, len(flag.Args()))
 	copy(files, flag.Args())
 
-	_, diags, err := Run(analyzers, files...)
-	if err != nil {
-		log.Fatal(err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// When stopped early, the partial results are still written to the
+	// outputs before exiting with an error.
+	_, diags, runErr := RunContext(ctx, analyzers, files...)
+	if runErr != nil && !errors.Is(runErr, context.Canceled) && !errors.Is(runErr, context.DeadlineExceeded) {
+		log.Fatal(runErr)
 	}
 
 	if len(diagnosticFilter) > 0 {
@@ -89,25 +125,31 @@ func Main(analyzers ...*analysis.Analyzer) {
 		})
 	}
 
-	for _, output := range outputTypes {
-		switch output {
-		case "color-text":
-			err = printer.ColorText(diags, os.Stdout)
-		case "text":
-			err = printer.Text(diags, os.Stdout)
-		case "json":
-			err = printer.JSON(diags, os.Stdout)
-		case "markdown":
-			// Incorporate dependencies into the list.
-			a, _ := dependencyOrder(analyzers)
-			err = printer.Markdown(diags, os.Stdout, a, version())
-		default:
-			panic("invalid output type")
-		}
-		if err != nil {
+	co, err := loadCodeOwners(codeOwnersPath, files)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if co != nil {
+		assignOwners(co, diags)
+	} else if len(ownerFilter) > 0 || groupByOwner {
+		log.Fatal("No CODEOWNERS file found. Specify one with -codeowners.")
+	}
+
+	if len(ownerFilter) > 0 {
+		diags = slices.DeleteFunc(diags, func(diag analysis.Diagnostic) bool {
+			return !ownedByAny(ownerFilter, &diag)
+		})
+	}
+
+	for _, o := range outputs {
+		if err = writeOutput(o, diags, analyzers); err != nil {
 			log.Fatal(err)
 		}
 	}
+
+	if runErr != nil {
+		log.Fatalf("Results are incomplete: %v", runErr)
+	}
 }
 
 //nolint:revive // This is used by a pseudo main function so allow exits.
@@ -124,10 +166,29 @@ func parseFlags(analyzers []*analysis.Analyzer) {
 	flag.Var(&analyzersFilter, "a", "Analyzers to run. By default all analyzers are included.")
 	flag.BoolVar(&fixFindings, "fix", false, "Run analyzers and write fixes to fields files. "+
 		"This will only execute the analyzers that support automatic fixing.")
+	flag.BoolVar(&suggestFixes, "suggest-fixes", false, "Attach suggested fixes to the findings of "+
+		"analyzers that support automatic fixing without modifying any files. This is enabled "+
+		"automatically for markdown and summary output.")
 	flag.Var(&diagnosticFilter, "i", "Include only diagnostics with a path containing this value. "+
 		"If specified more than once, then diagnostics that match any value are included.")
-	flag.Var(&outputTypes, "set-output", "Output type to use. Allowed types are color-text, text, "+
-		"markdown, and json. Defaults to color-text.")
+	flag.StringVar(&codeOwnersPath, "codeowners", "", "CODEOWNERS file used to assign owners to "+
+		"diagnostics. By default it is found by searching .github/CODEOWNERS, CODEOWNERS, and "+
+		"docs/CODEOWNERS in the directory of the first fields file and its parents.")
+	flag.Var(&ownerFilter, "owner", "Include only diagnostics owned by this code owner (e.g. "+
+		"@elastic/security-service-integrations). If specified more than once, then diagnostics "+
+		"owned by any value are included.")
+	flag.BoolVar(&groupByOwner, "group-by-owner", false, "Group the markdown output by code owner.")
+	flag.Var(&outputTypes, "set-output", "Output type to use with an optional destination file "+
+		"(type[=path]). Allowed types are "+strings.Join(outputTypeNames, ", ")+". "+
+		"May be specified more than once. Defaults to color-text written to stdout.")
+	flag.IntVar(&summaryTopN, "summary-top", 10, "Number of packages to list in the worst packages "+
+		"ranking of the summary and summary-json outputs.")
+	addLinkFlags(flag.CommandLine, &linkOverrides)
+	flag.DurationVar(&timeout, "timeout", 0, "Stop the analysis after this duration (e.g. 2m) and "+
+		"write the partial results. The exit code is non-zero when stopped. Zero means no timeout.")
+	flag.StringVar(&ecsSchemaPath, "ecs-schema", "", "Load ECS field definitions from an ecs_flat.yml file, "+
+		"prefixed with <version>= unless it is in an ECS repository checkout, or from a directory of "+
+		"<version>/ecs_flat.yml files. These take precedence over the embedded ECS definitions.")
 	flag.StringVar(&cpuprofile, "cpuprofile", "", "Write cpu profile to this file")
 
 	flag.Usage = func() {
@@ -149,6 +210,24 @@ func parseFlags(analyzers []*analysis.Analyzer) {
 		fmt.Fprintln(out, "")
 		fmt.Fprintln(out, "  fydler -i '/my_package/' packages/**/fields/*.yml")
 		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "Each output type can be written to its own file by appending")
+		fmt.Fprintln(out, "'=path' to the type. Outputs without a path are written to stdout.")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "  fydler -set-output json=report.json -set-output color-text packages/**/fields/*.yml")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "The template output renders diagnostics with a Go text/template file.")
+		fmt.Fprintln(out, "The destination is given after the template path.")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "  fydler -set-output template=report.tmpl=report.csv packages/**/fields/*.yml")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "Diagnostics are assigned owners from the CODEOWNERS file. Use -owner")
+		fmt.Fprintln(out, "to report only on the packages of a team.")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "  fydler -owner @elastic/obs-infraobs-integrations -group-by-owner -set-output markdown packages/**/fields/*.yml")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "Two JSON reports can be compared with the report-diff command.")
+		fmt.Fprintln(out, "See 'fydler report-diff -h'.")
+		fmt.Fprintln(out, "")
 		fmt.Fprintln(out, "The included analyzers are:")
 		fmt.Fprintln(out, "")
 
@@ -166,11 +245,18 @@ func parseFlags(analyzers []*analysis.Analyzer) {
 		fmt.Fprintln(out, "Version:", version())
 		fmt.Fprintln(out, "")
 
-		fmt.Fprintln(out, "Environment variables for markdown output:")
+		fmt.Fprintln(out, "Links and relative paths in the outputs are configured with the")
+		fmt.Fprintln(out, "-link-* flags or these environment variables. When unset, the values")
+		fmt.Fprintln(out, "are detected from GitHub Actions (GITHUB_SERVER_URL, GITHUB_REPOSITORY,")
+		fmt.Fprintln(out, "GITHUB_SHA, GITHUB_WORKSPACE), GitLab CI, or Bitbucket Pipelines.")
+		fmt.Fprintln(out, "Links default to elastic/integrations on GitHub at main.")
 		fmt.Fprintln(out, "")
-		fmt.Fprintln(out, "  GITHUB_REPOSITORY  GitHub owner/repo for links (default: elastic/integrations)")
-		fmt.Fprintln(out, "  GITHUB_SHA         Commit SHA for links (default: main)")
-		fmt.Fprintln(out, "  GITHUB_WORKSPACE   Repo root path to trim from file paths in links")
+		fmt.Fprintln(out, "  FYDLER_LINK_PROVIDER  github, ghes, gitlab, or bitbucket")
+		fmt.Fprintln(out, "  FYDLER_LINK_BASE_URL  Base URL of the source code host")
+		fmt.Fprintln(out, "  FYDLER_LINK_REPO      Repository path for links")
+		fmt.Fprintln(out, "  FYDLER_LINK_REF       Commit SHA, branch, or tag for links")
+		fmt.Fprintln(out, "  FYDLER_LINK_ROOT      Repo root path to trim from file paths")
+		fmt.Fprintln(out, "  FYDLER_LINK_TEMPLATE  Custom link URL template")
 		fmt.Fprintln(out, "")
 
 		fmt.Fprintln(out, "Flags:")
@@ -178,18 +264,25 @@ func parseFlags(analyzers []*analysis.Analyzer) {
 	}
 	flag.Parse()
 
-	for _, output := range outputTypes {
-		switch output {
-		case "color-text", "text", "markdown", "json":
-		default:
-			log.Printf("invalid output type %q", output)
+	for _, value := range outputTypes {
+		o, err := parseOutput(value, outputTypeNames)
+		if err != nil {
+			log.Print(err)
 			os.Exit(1)
 		}
+		outputs = append(outputs, o)
+	}
+	if len(outputs) == 0 {
+		outputs = []output{{Type: "color-text"}}
 	}
-	if len(outputTypes) == 0 {
-		outputTypes = []string{"color-text"}
+	if slices.ContainsFunc(outputs, func(o output) bool {
+		return o.Type == "markdown" || o.Type == "summary" || o.Type == "summary-json"
+	}) {
+		suggestFixes = true
 	}
 
+	configureLinks(linkOverrides, outputs)
+
 	// Split analyzer filters and validate the values.
 	var tmp []string
 	for _, a := range analyzersFilter {
@@ -211,7 +304,47 @@ nextFilter:
 	}
 }
 
+// addLinkFlags registers the flags that configure source links.
+func addLinkFlags(fs *flag.FlagSet, b *printer.LinkBuilder) {
+	fs.StringVar(&b.Provider, "link-provider", "", "Source code host used to build links to "+
+		"source locations. Allowed providers are "+strings.Join(printer.LinkProviders(), ", ")+". "+
+		"Defaults to the CI environment's host or github.")
+	fs.StringVar(&b.BaseURL, "link-base-url", "", "Base URL of the source code host "+
+		"(e.g. https://github.example.com). Required for ghes.")
+	fs.StringVar(&b.Repo, "link-repo", "", "Repository path used in links (e.g. elastic/integrations).")
+	fs.StringVar(&b.Ref, "link-ref", "", "Commit SHA, branch, or tag used in links.")
+	fs.StringVar(&b.Root, "link-root", "", "Repository root path to trim from file paths.")
+	fs.StringVar(&b.Template, "link-template", "", "Custom link URL template. It may use "+
+		"{base_url}, {repo}, {ref}, {path}, {line}, and {end_line}.")
+}
+
+// configureLinks sets the LinkBuilder used by the printers. The link
+// configuration is only validated if one of the outputs contains links.
+//
+//nolint:revive // This is used by a pseudo main function so allow exits.
+func configureLinks(overrides printer.LinkBuilder, outputs []output) {
+	links := printer.NewLinkBuilder(overrides)
+	if slices.ContainsFunc(outputs, hasLinks) {
+		if err := links.Validate(); err != nil {
+			log.Print(err)
+			os.Exit(1)
+		}
+	}
+	printer.SetLinkBuilder(links)
+}
+
+// Run runs the analyzers on the fields files. It is equivalent to RunContext
+// with a background context.
 func Run(analyzers []*analysis.Analyzer, files ...string) (results map[*analysis.Analyzer]any, diags []analysis.Diagnostic, err error) {
+	return RunContext(context.Background(), analyzers, files...)
+}
+
+// RunContext runs the analyzers on the fields files. If ctx is canceled, it
+// stops before running the next analyzer (analyzers may also stop early) and
+// returns the results of the analyzers that completed, the diagnostics that
+// were reported so far, and an error wrapping ctx.Err(). Fixes are not
+// written when the run is canceled.
+func RunContext(ctx context.Context, analyzers []*analysis.Analyzer, files ...string) (results map[*analysis.Analyzer]any, diags []analysis.Diagnostic, err error) {
 	slices.Sort(files)
 
 	// Honor the analyzers filter.
@@ -231,12 +364,21 @@ func Run(analyzers []*analysis.Analyzer, files ...string) (results map[*analysis
 		return nil, nil, err
 	}
 
-	fields, err := readFields(files...)
+	loaded, err := readFields(ctx, fixFindings, files...)
 	if err != nil {
 		return nil, nil, err
 	}
+	var fields []pkgspec.Field
+	for _, lf := range loaded {
+		fields = append(fields, lf.fields...)
+	}
 	slices.SortFunc(fields, compareFieldByFileMetadata)
 
+	packages, err := pkgmodel.Load(ctx, fieldsFilePaths(fields))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed loading packages: %w", err)
+	}
+
 	flatFields := pkgspec.FlattenFields(fields, nil)
 	flat := make([]pkgspec.Field, len(flatFields))
 	for i := range flatFields {
@@ -245,24 +387,34 @@ func Run(analyzers []*analysis.Analyzer, files ...string) (results map[*analysis
 	slices.SortFunc(flat, compareFieldByFileMetadata)
 
 	pass := &analysis.Pass{
-		Fields: toPointerSlice(fields),
-		Flat:   toPointerSlice(flat),
+		Context:  ctx,
+		Fields:   toPointerSlice(fields),
+		Flat:     toPointerSlice(flat),
+		Packages: packages,
 		Report: func(d analysis.Diagnostic) {
 			diags = append(diags, d)
 		},
 	}
 	results = map[*analysis.Analyzer]any{}
 
+	for _, lf := range loaded {
+		pass.CacheSource(lf.path, lf.data)
+	}
 	if fixFindings {
-		pass.AST, err = loadASTs(fields)
-		if err != nil {
-			return nil, nil, err
+		pass.AST = make(map[string]*analysis.AST, len(loaded))
+		for _, lf := range loaded {
+			pass.AST[lf.path] = &analysis.AST{File: lf.ast}
 		}
 	}
 
 	for _, a := range analyzers {
+		if err = ctx.Err(); err != nil {
+			return results, diags, fmt.Errorf("stopped before running %s analyzer: %w", a.Name, err)
+		}
+
 		pass.Analyzer = a
 		pass.Fix = fixFindings
+		pass.Suggest = suggestFixes && !fixFindings
 		pass.ResultOf = map[*analysis.Analyzer]any{}
 		for _, required := range a.Requires {
 			pass.ResultOf[required] = results[required]
@@ -270,8 +422,14 @@ func Run(analyzers []*analysis.Analyzer, files ...string) (results map[*analysis
 
 		result, err := a.Run(pass)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return results, diags, fmt.Errorf("stopped while running %s analyzer: %w", a.Name, ctxErr)
+			}
 			return nil, nil, fmt.Errorf("failed running %s analyzer: %w", a.Name, err)
 		}
+		if a.ResultType != nil && result != nil && reflect.TypeOf(result) != a.ResultType {
+			return nil, nil, fmt.Errorf("%s analyzer returned a %T, but its ResultType is %v", a.Name, result, a.ResultType)
+		}
 		results[a] = result
 	}
 
@@ -289,21 +447,15 @@ func Run(analyzers []*analysis.Analyzer, files ...string) (results map[*analysis
 	return results, diags, nil
 }
 
-func loadASTs(fields []pkgspec.Field) (map[string]*analysis.AST, error) {
-	m := map[string]*analysis.AST{}
-	for _, field := range fields {
-		if _, found := m[field.FilePath()]; found {
-			continue
-		}
-
-		f, err := parser.ParseFile(field.FilePath(), parser.ParseComments)
-		if err != nil {
-			return nil, fmt.Errorf("failed loading AST for %s: %w", field.FilePath(), err)
+// fieldsFilePaths returns the unique file paths of the sorted fields.
+func fieldsFilePaths(fields []pkgspec.Field) []string {
+	var paths []string
+	for _, f := range fields {
+		if n := len(paths); n == 0 || paths[n-1] != f.FilePath() {
+			paths = append(paths, f.FilePath())
 		}
-
-		m[field.FilePath()] = &analysis.AST{File: f}
 	}
-	return m, nil
+	return paths
 }
 
 func compareFieldByFileMetadata(a, b pkgspec.Field) int {
@@ -320,44 +472,6 @@ func compareFileMetadata(a, b pkgspec.FileMetadata) int {
 	return cmp.Compare(a.Column(), b.Column())
 }
 
-// readFields reads all files matching the given globs and returns all fields.
-func readFields(globs ...string) ([]pkgspec.Field, error) {
-	var matches []string
-	for _, glob := range globs {
-		m, err := filepath.Glob(glob)
-		if err != nil {
-			return nil, err
-		}
-		matches = append(matches, m...)
-	}
-
-	var fields []pkgspec.Field
-	for _, file := range matches {
-		ff, err := readFieldsFile(file)
-		if err != nil {
-			return nil, err
-		}
-		fields = append(fields, ff...)
-	}
-	return fields, nil
-}
-
-func readFieldsFile(path string) ([]pkgspec.Field, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	var fields []pkgspec.Field
-	if err = yaml.Unmarshal(data, &fields); err != nil {
-		return nil, fmt.Errorf("failed reading from %q: %w", path, err)
-	}
-
-	pkgspec.AnnotateFileMetadata(path, &fields)
-	pkgspec.AnnotateFieldPointers(fields)
-	return fields, nil
-}
-
 func compareAnalyzer(a, b *analysis.Analyzer) int {
 	return cmp.Compare(a.Name, b.Name)
 }
@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fydler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// loadedFile is a fields file that has been read and decoded.
+type loadedFile struct {
+	path   string
+	data   []byte          // Raw file contents.
+	fields []pkgspec.Field // Fields annotated with file metadata.
+	ast    *ast.File       // Editable AST. Only set when requested.
+}
+
+// readFields reads all files matching the given globs. Files are loaded
+// concurrently and returned in the order in which the globs matched them.
+// When parseAST is true the editable AST of each file is also parsed.
+func readFields(ctx context.Context, parseAST bool, globs ...string) ([]*loadedFile, error) {
+	var matches []string
+	for _, glob := range globs {
+		m, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m...)
+	}
+
+	return loadFiles(ctx, matches, parseAST, runtime.GOMAXPROCS(0))
+}
+
+// loadFiles loads the files using at most workers goroutines. The results are
+// in the same order as paths. If any file fails to load, the error for the
+// first such file in paths is returned so that errors are deterministic.
+func loadFiles(ctx context.Context, paths []string, parseAST bool, workers int) ([]*loadedFile, error) {
+	results := make([]*loadedFile, len(paths))
+	errs := make([]error, len(paths))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(paths)) {
+		wg.Go(func() {
+			for i := range jobs {
+				results[i], errs[i] = loadFile(paths[i], parseAST)
+			}
+		})
+	}
+
+	func() {
+		defer close(jobs)
+		for i := range paths {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// loadFile reads a fields file once and derives both the fields and,
+// optionally, the editable AST from the same contents.
+func loadFile(path string, parseAST bool) (*loadedFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lf := &loadedFile{path: path, data: data}
+	if err = yaml.Unmarshal(data, &lf.fields); err != nil {
+		return nil, fmt.Errorf("failed reading from %q: %w", path, err)
+	}
+	pkgspec.AnnotateFileMetadata(path, &lf.fields)
+	pkgspec.AnnotateFieldPointers(lf.fields)
+
+	if parseAST {
+		if lf.ast, err = parser.ParseBytes(data, parser.ParseComments); err != nil {
+			return nil, fmt.Errorf("failed loading AST for %s: %w", path, err)
+		}
+		lf.ast.Name = path
+	}
+
+	return lf, nil
+}
@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fydler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	var paths []string
+	for i := range 50 {
+		path := filepath.Join(dir, fmt.Sprintf("fields-%02d.yml", i))
+		data := fmt.Sprintf("- name: field%d\n  type: keyword\n", i)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+		paths = append(paths, path)
+	}
+
+	loaded, err := loadFiles(context.Background(), paths, true, 4)
+	require.NoError(t, err)
+	require.Len(t, loaded, len(paths))
+
+	for i, lf := range loaded {
+		assert.Equal(t, paths[i], lf.path)
+		require.Len(t, lf.fields, 1)
+		assert.Equal(t, fmt.Sprintf("field%d", i), lf.fields[0].Name)
+		assert.Equal(t, paths[i], lf.fields[0].FilePath())
+		require.NotNil(t, lf.ast)
+		assert.Equal(t, string(lf.data), lf.ast.String())
+	}
+
+	t.Run("no_ast", func(t *testing.T) {
+		loaded, err := loadFiles(context.Background(), paths[:1], false, 4)
+		require.NoError(t, err)
+		assert.Nil(t, loaded[0].ast)
+	})
+
+	t.Run("first_error", func(t *testing.T) {
+		bad := []string{paths[0], filepath.Join(dir, "missing-1.yml"), filepath.Join(dir, "missing-2.yml")}
+		for range 10 {
+			_, err := loadFiles(context.Background(), bad, false, 3)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "missing-1.yml")
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := loadFiles(ctx, paths, false, 4)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fydler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/printer"
+)
+
+// outputTypeNames lists the allowed output types.
+var outputTypeNames = []string{
+	"color-text",
+	"text",
+	"pretty",
+	"markdown",
+	"html",
+	"summary",
+	"summary-json",
+	"json",
+	"checkstyle",
+	"gitlab-codequality",
+	"template",
+}
+
+// output is an output type and the destination to which it is written.
+type output struct {
+	Type     string
+	Path     string // Destination file path. Empty or "-" means stdout.
+	Template string // Template file path used by the template type.
+}
+
+// parseOutput parses an output specification of the form type[=path]. The
+// type must be one of the allowed names. The template type requires the path
+// to the template file and accepts an optional destination
+// (template=tmpl_path[=path]).
+func parseOutput(value string, allowed []string) (output, error) {
+	typ, path, _ := strings.Cut(value, "=")
+	if !slices.Contains(allowed, typ) {
+		return output{}, fmt.Errorf("invalid output type %q", typ)
+	}
+
+	o := output{Type: typ, Path: path}
+	if typ == "template" {
+		o.Template, o.Path, _ = strings.Cut(path, "=")
+		if o.Template == "" {
+			return output{}, errors.New("template output requires a template file (template=path.tmpl)")
+		}
+	}
+	return o, nil
+}
+
+// writeOutput prints the diagnostics to the output's destination.
+func writeOutput(o output, diags []analysis.Diagnostic, analyzers []*analysis.Analyzer) error {
+	return withDestination(o, func(w io.Writer) error {
+		return printOutput(w, o, diags, analyzers)
+	})
+}
+
+// withDestination calls fn with a writer for the output's destination. The
+// destination file is closed after fn returns.
+func withDestination(o output, fn func(w io.Writer) error) (err error) {
+	if isStdout(o) {
+		return fn(os.Stdout)
+	}
+
+	f, err := os.Create(o.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s output file: %w", o.Type, err)
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	return fn(f)
+}
+
+// isStdout returns true if the output is written to stdout.
+func isStdout(o output) bool {
+	return o.Path == "" || o.Path == "-"
+}
+
+// hasLinks returns true if the output contains links to source locations.
+func hasLinks(o output) bool {
+	switch o.Type {
+	case "markdown", "html", "template":
+		return true
+	default:
+		return false
+	}
+}
+
+func printOutput(w io.Writer, o output, diags []analysis.Diagnostic, analyzers []*analysis.Analyzer) error {
+	switch o.Type {
+	case "color-text":
+		return printer.ColorText(diags, w)
+	case "text":
+		return printer.Text(diags, w)
+	case "pretty":
+		// Color is only used on a terminal, never in a destination file.
+		if isStdout(o) && !color.NoColor {
+			return printer.ColorPretty(diags, w)
+		}
+		return printer.Pretty(diags, w)
+	case "json":
+		a, _ := dependencyOrder(analyzers)
+		return printer.JSON(diags, w, a, version(), os.Args[1:])
+	case "checkstyle":
+		return printer.Checkstyle(diags, w)
+	case "gitlab-codequality":
+		return printer.GitLabCodeQuality(diags, w)
+	case "markdown":
+		// Incorporate dependencies into the list.
+		a, _ := dependencyOrder(analyzers)
+		if groupByOwner {
+			return printer.MarkdownByOwner(diags, w, a, version())
+		}
+		return printer.Markdown(diags, w, a, version())
+	case "html":
+		a, _ := dependencyOrder(analyzers)
+		return printer.HTML(diags, w, a, version())
+	case "summary":
+		return printer.SummaryText(diags, w, summaryTopN)
+	case "summary-json":
+		return printer.SummaryJSON(diags, w, summaryTopN)
+	case "template":
+		a, _ := dependencyOrder(analyzers)
+		return printer.Template(diags, w, a, version(), o.Template)
+	default:
+		panic("invalid output type")
+	}
+}
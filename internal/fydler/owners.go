@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fydler
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+	"github.com/andrewkroh/fydler/internal/codeowners"
+)
+
+// loadCodeOwners loads the CODEOWNERS file at path. When path is empty, the
+// file is auto-detected by searching from the directory of the first input
+// file upward. It returns nil when no CODEOWNERS file is found.
+func loadCodeOwners(path string, files []string) (*codeowners.File, error) {
+	if path == "" {
+		dir := "."
+		if len(files) > 0 {
+			dir = filepath.Dir(files[0])
+		}
+
+		var found bool
+		if path, found = codeowners.Find(dir); !found {
+			return nil, nil
+		}
+	}
+
+	return codeowners.Load(path)
+}
+
+// assignOwners sets the owners of each diagnostic based on its file.
+func assignOwners(co *codeowners.File, diags []analysis.Diagnostic) {
+	owners := map[string][]string{}
+	for i := range diags {
+		file := diags[i].Pos.File
+		o, found := owners[file]
+		if !found {
+			o = co.Owners(file)
+			owners[file] = o
+		}
+		diags[i].Owners = o
+	}
+}
+
+// ownedByAny returns true if the diagnostic is owned by any of the owners.
+func ownedByAny(owners []string, diag *analysis.Diagnostic) bool {
+	for _, o := range diag.Owners {
+		if slices.Contains(owners, o) {
+			return true
+		}
+	}
+	return false
+}
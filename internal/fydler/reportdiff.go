@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fydler
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/andrewkroh/fydler/internal/printer"
+)
+
+// reportDiffOutputTypeNames lists the allowed output types of report-diff.
+var reportDiffOutputTypeNames = []string{
+	"text",
+	"markdown",
+	"json",
+}
+
+// reportDiffMain implements the report-diff command that compares two JSON
+// reports.
+//
+//nolint:revive // This is a pseudo main function so allow exits.
+func reportDiffMain(args []string) {
+	fs := flag.NewFlagSet("report-diff", flag.ExitOnError)
+	var outputTypes stringListFlag
+	fs.Var(&outputTypes, "set-output", "Output type to use with an optional destination file "+
+		"(type[=path]). Allowed types are text, markdown, and json. "+
+		"May be specified more than once. Defaults to text written to stdout.")
+	var linkOverrides printer.LinkBuilder
+	addLinkFlags(fs, &linkOverrides)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintln(out, "fydler report-diff [flags] old.json new.json")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "report-diff compares two reports created with '-set-output json'")
+		fmt.Fprintln(out, "and shows which diagnostics were added, removed, or unchanged.")
+		fmt.Fprintln(out, "Diagnostics are matched by analyzer, field name, and file path")
+		fmt.Fprintln(out, "relative to the repository root so line number changes and")
+		fmt.Fprintln(out, "different checkout locations do not affect the result.")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Flags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args) //nolint:errcheck // ExitOnError
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var outputs []output
+	for _, value := range outputTypes {
+		o, err := parseOutput(value, reportDiffOutputTypeNames)
+		if err != nil {
+			log.Print(err)
+			os.Exit(1)
+		}
+		outputs = append(outputs, o)
+	}
+	if len(outputs) == 0 {
+		outputs = []output{{Type: "text"}}
+	}
+
+	configureLinks(linkOverrides, outputs)
+
+	oldReport, err := readReport(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	newReport, err := readReport(fs.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	diff := printer.DiffReports(oldReport, newReport)
+
+	for _, o := range outputs {
+		err = withDestination(o, func(w io.Writer) error {
+			switch o.Type {
+			case "text":
+				return printer.DiffText(diff, w)
+			case "markdown":
+				return printer.DiffMarkdown(diff, w, version())
+			case "json":
+				return printer.DiffJSON(diff, w)
+			default:
+				panic("invalid output type")
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func readReport(path string) (*printer.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := printer.ReadReport(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading report from %q: %w", path, err)
+	}
+	return r, nil
+}
@@ -0,0 +1,350 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package jsonwalk walks the fields of JSON documents, such as sample events
+// and pipeline test outputs, while tracking the location of each key.
+package jsonwalk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+)
+
+// Kind is the kind of a JSON value.
+type Kind int
+
+const (
+	Scalar Kind = iota // String, number, boolean, or null.
+	Array
+	Object
+)
+
+// Span is the location of a JSON object key, including its quotes.
+type Span struct {
+	Line   int
+	Col    int
+	EndCol int // Column after the closing quote.
+}
+
+// Visitor is called for each key-value pair with the dotted field path, the
+// kind of value, the value, and the location of the key. Object values are
+// visited after their children and their value is nil. A scalar value is a
+// string, json.Number, bool, or nil. An array value is a []any of its
+// decoded elements, where objects are map[string]any. Array elements are not
+// visited.
+type Visitor func(path string, kind Kind, value any, span Span)
+
+// SampleEventFile returns the path of the sample event of a data stream
+// directory. The file might not exist.
+func SampleEventFile(dir string) string {
+	return filepath.Join(dir, "sample_event.json")
+}
+
+// PipelineTestFiles returns the expected outputs of the pipeline tests of a
+// data stream directory.
+func PipelineTestFiles(dir string) []string {
+	matches, _ := filepath.Glob(filepath.Join(dir, "_dev", "test", "pipeline", "test-*-expected.json"))
+	return matches
+}
+
+// Document walks the top-level JSON object in data (e.g. a sample_event.json).
+// Nothing is visited if data does not begin with a JSON object.
+func Document(ctx context.Context, data []byte, fn Visitor) {
+	lineTable := buildLineTable(data)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// Read opening '{'.
+	t, err := dec.Token()
+	if err != nil || t != json.Delim('{') {
+		return
+	}
+
+	walkObject(ctx, dec, "", data, lineTable, fn)
+}
+
+// PipelineTestExpected walks each document in the "expected" array of a
+// pipeline test output (e.g. test-foo.log-expected.json).
+func PipelineTestExpected(ctx context.Context, data []byte, fn Visitor) {
+	PipelineTestDocuments(ctx, data, fn, nil)
+}
+
+// PipelineTestDocuments is like PipelineTestExpected, but it also calls done
+// after each document has been walked so that the fields of a document can
+// be checked together. done may be nil.
+func PipelineTestDocuments(ctx context.Context, data []byte, fn Visitor, done func()) {
+	lineTable := buildLineTable(data)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// Read opening '{' of outer object.
+	t, err := dec.Token()
+	if err != nil || t != json.Delim('{') {
+		return
+	}
+
+	// Find the "expected" key and walk each document in its array.
+	for dec.More() {
+		kt, err := dec.Token()
+		if err != nil {
+			break
+		}
+		key, ok := kt.(string)
+		if !ok {
+			break
+		}
+		if key != "expected" {
+			skipOneValue(dec)
+			continue
+		}
+
+		// Read opening '[' of expected array.
+		t, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if t != json.Delim('[') {
+			break
+		}
+
+		// Walk each document in the expected array.
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				break
+			}
+			if t != json.Delim('{') {
+				skipAfterToken(dec, t)
+				continue
+			}
+			walkObject(ctx, dec, "", data, lineTable, fn)
+			if done != nil && ctx.Err() == nil {
+				done()
+			}
+		}
+		break
+	}
+}
+
+// walkObject walks a JSON object after '{' has been consumed, calling fn
+// for each key-value pair with the dotted field path, the kind of value, and
+// the location of the key. Keys are visited in sorted order for
+// deterministic output. Walking stops without visiting any keys if ctx is
+// canceled.
+func walkObject(ctx context.Context, dec *json.Decoder, prefix string, data []byte, lineTable []int, fn Visitor) {
+	// Collect all key-value entries first to sort by key.
+	type entry struct {
+		path  string
+		kind  Kind
+		value any
+		span  Span
+	}
+	var entries []entry
+
+	for dec.More() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		kt, err := dec.Token()
+		if err != nil {
+			break
+		}
+		key, ok := kt.(string)
+		if !ok {
+			break
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		span := keySpanAt(data, lineTable, int(dec.InputOffset()))
+
+		vt, err := dec.Token()
+		if err != nil {
+			break
+		}
+
+		if d, ok := vt.(json.Delim); ok {
+			switch d {
+			case '{':
+				entries = append(entries, entry{path: path, kind: Object, span: span})
+				walkObject(ctx, dec, path, data, lineTable, fn)
+			case '[':
+				entries = append(entries, entry{path: path, kind: Array, value: readArray(dec), span: span})
+			}
+		} else {
+			entries = append(entries, entry{path: path, kind: Scalar, value: vt, span: span})
+		}
+	}
+	// Read closing '}'.
+	dec.Token() //nolint:errcheck
+
+	// Sort entries by path for deterministic output.
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].path < entries[j].path
+	})
+	for _, e := range entries {
+		fn(e.path, e.kind, e.value, e.span)
+	}
+}
+
+// --- JSON token helpers ---
+
+func skipOneValue(dec *json.Decoder) {
+	t, err := dec.Token()
+	if err != nil {
+		return
+	}
+	skipAfterToken(dec, t)
+}
+
+func skipAfterToken(dec *json.Decoder, t json.Token) {
+	if d, ok := t.(json.Delim); ok {
+		switch d {
+		case '{':
+			skipObject(dec)
+		case '[':
+			skipArray(dec)
+		}
+	}
+}
+
+// readValue reads the remainder of the value that begins with t.
+func readValue(dec *json.Decoder, t json.Token) any {
+	d, ok := t.(json.Delim)
+	if !ok {
+		return t
+	}
+	switch d {
+	case '{':
+		return readObject(dec)
+	case '[':
+		return readArray(dec)
+	}
+	return nil
+}
+
+func readObject(dec *json.Decoder) map[string]any {
+	obj := map[string]any{}
+	for dec.More() {
+		kt, err := dec.Token()
+		if err != nil {
+			break
+		}
+		key, _ := kt.(string)
+		t, err := dec.Token()
+		if err != nil {
+			break
+		}
+		obj[key] = readValue(dec, t)
+	}
+	dec.Token() //nolint:errcheck // '}'
+	return obj
+}
+
+func readArray(dec *json.Decoder) []any {
+	arr := []any{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			break
+		}
+		arr = append(arr, readValue(dec, t))
+	}
+	dec.Token() //nolint:errcheck // ']'
+	return arr
+}
+
+func skipObject(dec *json.Decoder) {
+	for dec.More() {
+		dec.Token() //nolint:errcheck // key
+		skipOneValue(dec)
+	}
+	dec.Token() //nolint:errcheck // '}'
+}
+
+func skipArray(dec *json.Decoder) {
+	for dec.More() {
+		skipOneValue(dec)
+	}
+	dec.Token() //nolint:errcheck // ']'
+}
+
+// --- Line number helpers ---
+
+// buildLineTable returns the byte offset of the start of each line (1-indexed).
+func buildLineTable(data []byte) []int {
+	table := []int{0} // line 1 starts at offset 0
+	for i, b := range data {
+		if b == '\n' {
+			table = append(table, i+1)
+		}
+	}
+	return table
+}
+
+// offsetToLine returns the 1-indexed line number for a byte offset.
+func offsetToLine(lineTable []int, offset int) int {
+	lo, hi := 0, len(lineTable)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if lineTable[mid] <= offset {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo + 1
+}
+
+// keySpanAt returns the span of the JSON key whose closing quote ends at
+// the byte offset.
+func keySpanAt(data []byte, lineTable []int, end int) Span {
+	line := offsetToLine(lineTable, end)
+	lineStart := lineTable[line-1]
+
+	// Scan backwards for the opening quote, skipping escaped quotes.
+	start := end - 1
+	for start > lineStart {
+		start--
+		if data[start] == '"' && !escaped(data, start) {
+			break
+		}
+	}
+
+	return Span{
+		Line:   line,
+		Col:    start - lineStart + 1,
+		EndCol: end - lineStart + 1,
+	}
+}
+
+// escaped returns true if the byte at idx is preceded by an odd number of
+// backslashes.
+func escaped(data []byte, idx int) bool {
+	n := 0
+	for i := idx - 1; i >= 0 && data[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
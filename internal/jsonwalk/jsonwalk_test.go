@@ -0,0 +1,99 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package jsonwalk
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type visit struct {
+	Path  string
+	Kind  Kind
+	Value any
+	Span  Span
+}
+
+func collect(walk func(context.Context, []byte, Visitor), ctx context.Context, data string) []visit {
+	var visits []visit
+	walk(ctx, []byte(data), func(path string, kind Kind, value any, span Span) {
+		visits = append(visits, visit{path, kind, value, span})
+	})
+	return visits
+}
+
+func TestDocument(t *testing.T) {
+	data := `{
+  "message": "hello",
+  "host": {"name": "a", "ip": ["10.0.0.1"]},
+  "tags": [{"nested": true}, 1]
+}`
+
+	assert.Equal(t, []visit{
+		{"host.ip", Array, []any{"10.0.0.1"}, Span{Line: 3, Col: 25, EndCol: 29}},
+		{"host.name", Scalar, "a", Span{Line: 3, Col: 12, EndCol: 18}},
+		{"host", Object, nil, Span{Line: 3, Col: 3, EndCol: 9}},
+		{"message", Scalar, "hello", Span{Line: 2, Col: 3, EndCol: 12}},
+		{"tags", Array, []any{map[string]any{"nested": true}, json.Number("1")}, Span{Line: 4, Col: 3, EndCol: 9}},
+	}, collect(Document, context.Background(), data))
+
+	assert.Empty(t, collect(Document, context.Background(), `["not", "an", "object"]`))
+}
+
+func TestPipelineTestExpected(t *testing.T) {
+	data := `{
+  "ignored": {"a": 1},
+  "expected": [
+    {"event": {"kind": "event"}},
+    null,
+    {"message": null}
+  ]
+}`
+
+	assert.Equal(t, []visit{
+		{"event.kind", Scalar, "event", Span{Line: 4, Col: 16, EndCol: 22}},
+		{"event", Object, nil, Span{Line: 4, Col: 6, EndCol: 13}},
+		{"message", Scalar, nil, Span{Line: 6, Col: 6, EndCol: 15}},
+	}, collect(PipelineTestExpected, context.Background(), data))
+}
+
+func TestPipelineTestDocuments(t *testing.T) {
+	data := `{"expected": [{"a": 1, "b": 2}, {"c": 3}]}`
+
+	var docs [][]string
+	var doc []string
+	PipelineTestDocuments(context.Background(), []byte(data), func(path string, _ Kind, _ any, _ Span) {
+		doc = append(doc, path)
+	}, func() {
+		docs = append(docs, doc)
+		doc = nil
+	})
+
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, docs)
+}
+
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Empty(t, collect(Document, ctx, `{"host": {"name": "a"}}`))
+	assert.Empty(t, collect(PipelineTestExpected, ctx, `{"expected": [{"host": {"name": "a"}}]}`))
+}
@@ -0,0 +1,109 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package pipelinewalk walks the processors of Elasticsearch ingest pipelines
+// while keeping the positions of their YAML nodes.
+package pipelinewalk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Visitor is called for each processor with the node containing the
+// processor type (e.g. "set") and the mapping node of its configuration.
+type Visitor func(typ, config *yaml.Node)
+
+// Files returns the ingest pipelines of a data stream directory. Pipelines may
+// be written in YAML or JSON.
+func Files(dir string) []string {
+	var files []string
+	for _, ext := range []string{"*.yml", "*.json"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, "elasticsearch", "ingest_pipeline", ext))
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// File parses the ingest pipeline at path and walks its processors.
+func File(path string, fn Visitor) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", path, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s does not contain an ingest pipeline", path)
+	}
+
+	root := doc.Content[0]
+	Processors(MappingValue(root, "processors"), fn)
+	Processors(MappingValue(root, "on_failure"), fn)
+	return nil
+}
+
+// Processors walks a sequence of processors, including the processors nested
+// in foreach and on_failure. Nested processors are visited after the
+// processor that contains them.
+func Processors(seq *yaml.Node, fn Visitor) {
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, procNode := range seq.Content {
+		if procNode.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for i := 0; i < len(procNode.Content)-1; i += 2 {
+			typ := procNode.Content[i]
+			config := procNode.Content[i+1]
+			if config.Kind != yaml.MappingNode {
+				continue
+			}
+
+			fn(typ, config)
+
+			if typ.Value == "foreach" {
+				if inner := MappingValue(config, "processor"); inner != nil && inner.Kind == yaml.MappingNode {
+					Processors(&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{inner}}, fn)
+				}
+			}
+			Processors(MappingValue(config, "on_failure"), fn)
+		}
+	}
+}
+
+// MappingValue returns the value of the key in a mapping node, or nil.
+func MappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
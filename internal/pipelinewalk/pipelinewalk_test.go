@@ -0,0 +1,67 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipelinewalk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.yml")
+	err := os.WriteFile(path, []byte(`---
+processors:
+  - set:
+      field: a
+  - foreach:
+      field: list
+      processor:
+        append:
+          field: b
+  - rename:
+      field: c
+      on_failure:
+        - remove:
+            field: d
+on_failure:
+  - set:
+      field: e
+`), 0o644)
+	require.NoError(t, err)
+
+	var visited []string
+	err = File(path, func(typ, config *yaml.Node) {
+		visited = append(visited, typ.Value+":"+MappingValue(config, "field").Value)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"set:a", "foreach:list", "append:b", "rename:c", "remove:d", "set:e"}, visited)
+}
+
+func TestFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`- not a pipeline`), 0o644))
+
+	err := File(path, func(_, _ *yaml.Node) { t.Fatal("unexpected processor") })
+	assert.ErrorContains(t, err, "does not contain an ingest pipeline")
+}
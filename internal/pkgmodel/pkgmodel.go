@@ -0,0 +1,440 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package pkgmodel loads the structure of Elastic packages. A package is
+// identified by its manifest.yml, and from there its data streams,
+// transforms, build manifest, and fields files are discovered. The model
+// records which package, data stream, or transform each fields file belongs
+// to so that analyzers do not need to infer structure from paths.
+//
+// A manifest that cannot be read does not stop the loading of other packages.
+// The error is kept on the model, and the analyzers that need the data
+// report it.
+package pkgmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest contains the attributes of a package's manifest.yml that are
+// used by analyzers.
+type Manifest struct {
+	Name          string `yaml:"name"`
+	Title         string `yaml:"title"`
+	Version       string `yaml:"version"`
+	Type          string `yaml:"type"` // integration, input, or content
+	FormatVersion string `yaml:"format_version"`
+	Conditions    struct {
+		Kibana struct {
+			Version string `yaml:"version"`
+		} `yaml:"kibana"`
+	} `yaml:"conditions"`
+}
+
+// KibanaVersion returns the Kibana version constraint of the package.
+func (m *Manifest) KibanaVersion() string {
+	return m.Conditions.Kibana.Version
+}
+
+// DataStreamManifest contains the attributes of a data stream's manifest.yml
+// that are used by analyzers.
+type DataStreamManifest struct {
+	Title         string `yaml:"title"`
+	Type          string `yaml:"type"` // logs, metrics, traces, or synthetics
+	Dataset       string `yaml:"dataset"`
+	Elasticsearch struct {
+		IndexMode     string        `yaml:"index_mode"`
+		IndexTemplate IndexTemplate `yaml:"index_template"`
+	} `yaml:"elasticsearch"`
+}
+
+// IndexTemplate contains the index template customizations of a data stream.
+type IndexTemplate struct {
+	Settings map[string]any `yaml:"settings"`
+	Mappings map[string]any `yaml:"mappings"`
+}
+
+// Package is an Elastic package.
+type Package struct {
+	Root        string   // Directory containing manifest.yml.
+	Manifest    Manifest // Contents of manifest.yml.
+	ManifestErr error    // Error reading manifest.yml.
+
+	// BuildManifest is the contents of _dev/build/build.yml. It is nil when
+	// the package does not have a build manifest or when it cannot be read.
+	BuildManifest    *pkgspec.BuildManifest
+	BuildManifestErr error // Error reading an existing build.yml.
+
+	DataStreams []*DataStream // Sorted by name.
+	Transforms  []*Transform  // Sorted by name.
+	FieldsFiles []string      // Package level fields files (used by input packages).
+}
+
+// Name returns the name of the package from its manifest. It falls back to
+// the name of the root directory if the manifest does not declare one.
+func (p *Package) Name() string {
+	if p.Manifest.Name != "" {
+		return p.Manifest.Name
+	}
+	return filepath.Base(p.Root)
+}
+
+// BuildManifestPath returns the path to the package's _dev/build/build.yml.
+func (p *Package) BuildManifestPath() string {
+	return filepath.Join(p.Root, "_dev", "build", "build.yml")
+}
+
+// DataStream is a data stream within a package.
+type DataStream struct {
+	Package     *Package
+	Name        string             // Directory name.
+	Dir         string             // Data stream directory.
+	Manifest    DataStreamManifest // Contents of manifest.yml.
+	ManifestErr error              // Error reading an existing manifest.yml.
+	FieldsFiles []string
+}
+
+// Dataset returns the dataset name of the data stream. It defaults to
+// <package>.<data stream> when the manifest does not declare one.
+func (ds *DataStream) Dataset() string {
+	if ds.Manifest.Dataset != "" {
+		return ds.Manifest.Dataset
+	}
+	return ds.Package.Manifest.Name + "." + ds.Name
+}
+
+// Transform is a transform within a package.
+type Transform struct {
+	Package     *Package
+	Name        string // Directory name.
+	Dir         string // Transform directory.
+	FieldsFiles []string
+}
+
+// Owner identifies what a fields file belongs to. Package is always set.
+// At most one of DataStream and Transform is set. When neither is set the
+// file is a package level fields file.
+type Owner struct {
+	Package    *Package
+	DataStream *DataStream
+	Transform  *Transform
+}
+
+// Dir returns the directory that contains the owner's fields directory.
+func (o Owner) Dir() string {
+	switch {
+	case o.DataStream != nil:
+		return o.DataStream.Dir
+	case o.Transform != nil:
+		return o.Transform.Dir
+	default:
+		return o.Package.Root
+	}
+}
+
+// FieldsFiles returns all fields files of the owner, including the ones that
+// were not requested.
+func (o Owner) FieldsFiles() []string {
+	switch {
+	case o.DataStream != nil:
+		return o.DataStream.FieldsFiles
+	case o.Transform != nil:
+		return o.Transform.FieldsFiles
+	default:
+		return o.Package.FieldsFiles
+	}
+}
+
+// Description returns a description of the owner for use in messages, such
+// as "data stream logs".
+func (o Owner) Description() string {
+	switch {
+	case o.DataStream != nil:
+		return "data stream " + o.DataStream.Name
+	case o.Transform != nil:
+		return "transform " + o.Transform.Name
+	default:
+		return "package " + o.Package.Name()
+	}
+}
+
+// Model contains the packages associated with a set of fields files.
+type Model struct {
+	Packages []*Package // Sorted by root directory.
+
+	owners map[string]Owner // Keyed by cleaned fields file path.
+}
+
+// Owner returns the owner of a fields file. It returns false if the file
+// does not belong to a package.
+func (m *Model) Owner(path string) (Owner, bool) {
+	o, found := m.owners[filepath.Clean(path)]
+	return o, found
+}
+
+// ForEachOwner groups the fields by the data stream, transform, or package
+// that owns them and calls fn for each owner in directory order. Fields
+// outside a package are skipped. It returns the first error from fn. The
+// checks done by fn stop early when ctx is canceled, so ForEachOwner then
+// returns ctx.Err() because the results are incomplete.
+func (m *Model) ForEachOwner(ctx context.Context, fields []*pkgspec.Field, fn func(Owner, []*pkgspec.Field) error) error {
+	owners := map[string]Owner{}
+	fieldsByDir := map[string][]*pkgspec.Field{}
+	for _, f := range fields {
+		owner, found := m.Owner(f.FilePath())
+		if !found {
+			continue
+		}
+		dir := owner.Dir()
+		owners[dir] = owner
+		fieldsByDir[dir] = append(fieldsByDir[dir], f)
+	}
+
+	dirs := make([]string, 0, len(owners))
+	for dir := range owners {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(owners[dir], fieldsByDir[dir]); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// Load loads the packages that contain the given fields files. Each package
+// is found by searching the parent directories of a fields file for a
+// package manifest.yml, and it is loaded only once. Fields files that are not
+// within a package are ignored.
+func Load(ctx context.Context, fieldsFiles []string) (*Model, error) {
+	l := &loader{
+		roots:    map[string]string{},
+		packages: map[string]*Package{},
+	}
+
+	m := &Model{owners: map[string]Owner{}}
+	for _, path := range fieldsFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		root, err := l.findRoot(filepath.Dir(filepath.Clean(path)))
+		if err != nil {
+			return nil, err
+		}
+		if root == "" {
+			continue
+		}
+		if _, found := l.packages[root]; found {
+			continue
+		}
+
+		pkg, err := loadPackage(root)
+		if err != nil {
+			return nil, err
+		}
+		l.packages[root] = pkg
+		m.add(pkg)
+	}
+
+	slices.SortFunc(m.Packages, func(a, b *Package) int {
+		return strings.Compare(a.Root, b.Root)
+	})
+	return m, nil
+}
+
+func (m *Model) add(pkg *Package) {
+	m.Packages = append(m.Packages, pkg)
+	for _, path := range pkg.FieldsFiles {
+		m.owners[path] = Owner{Package: pkg}
+	}
+	for _, ds := range pkg.DataStreams {
+		for _, path := range ds.FieldsFiles {
+			m.owners[path] = Owner{Package: pkg, DataStream: ds}
+		}
+	}
+	for _, t := range pkg.Transforms {
+		for _, path := range t.FieldsFiles {
+			m.owners[path] = Owner{Package: pkg, Transform: t}
+		}
+	}
+}
+
+type loader struct {
+	roots    map[string]string   // Directory to package root ("" if none).
+	packages map[string]*Package // Keyed by root.
+}
+
+// findRoot returns the package root containing dir or an empty string if
+// dir is not in a package.
+func (l *loader) findRoot(dir string) (string, error) {
+	var visited []string
+	root := ""
+	for d := dir; ; d = filepath.Dir(d) {
+		if r, found := l.roots[d]; found {
+			root = r
+			break
+		}
+		visited = append(visited, d)
+
+		isRoot, err := isPackageRoot(d)
+		if err != nil {
+			return "", err
+		}
+		if isRoot {
+			root = d
+			break
+		}
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+
+	for _, d := range visited {
+		l.roots[d] = root
+	}
+	return root, nil
+}
+
+// isPackageRoot returns true if dir contains a package manifest.yml. Data
+// stream directories also contain a manifest.yml so only manifests that
+// declare a format_version are considered. A manifest that is not valid YAML
+// is assumed to belong to a package unless it is in a data stream or
+// transform directory.
+func isPackageRoot(dir string) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.yml"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var m Manifest
+	if err = yaml.Unmarshal(data, &m); err != nil {
+		switch filepath.Base(filepath.Dir(dir)) {
+		case "data_stream", "transform":
+			return false, nil
+		}
+		return true, nil
+	}
+	return m.FormatVersion != "", nil
+}
+
+func loadPackage(root string) (*Package, error) {
+	pkg := &Package{Root: root}
+	pkg.ManifestErr = readYAML(filepath.Join(root, "manifest.yml"), &pkg.Manifest)
+
+	var build pkgspec.BuildManifest
+	if err := readYAML(pkg.BuildManifestPath(), &build); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			pkg.BuildManifestErr = err
+		}
+	} else {
+		pkg.BuildManifest = &build
+	}
+
+	var err error
+	if pkg.FieldsFiles, err = globFieldsFiles(root); err != nil {
+		return nil, err
+	}
+
+	dataStreamDirs, err := subdirectories(filepath.Join(root, "data_stream"))
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dataStreamDirs {
+		ds := &DataStream{Package: pkg, Name: filepath.Base(dir), Dir: dir}
+		if err = readYAML(filepath.Join(dir, "manifest.yml"), &ds.Manifest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			ds.ManifestErr = err
+		}
+		if ds.FieldsFiles, err = globFieldsFiles(dir); err != nil {
+			return nil, err
+		}
+		pkg.DataStreams = append(pkg.DataStreams, ds)
+	}
+
+	transformDirs, err := subdirectories(filepath.Join(root, "elasticsearch", "transform"))
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range transformDirs {
+		t := &Transform{Package: pkg, Name: filepath.Base(dir), Dir: dir}
+		if t.FieldsFiles, err = globFieldsFiles(dir); err != nil {
+			return nil, err
+		}
+		pkg.Transforms = append(pkg.Transforms, t)
+	}
+
+	return pkg, nil
+}
+
+// globFieldsFiles returns the fields files in dir/fields.
+func globFieldsFiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "fields", "*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(matches)
+	return matches, nil
+}
+
+// subdirectories returns the sorted subdirectories of dir. It returns an
+// empty list if dir does not exist.
+func subdirectories(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs = append(dirs, filepath.Join(dir, e.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+func readYAML(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", path, err)
+	}
+	return nil
+}
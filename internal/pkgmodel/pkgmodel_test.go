@@ -0,0 +1,169 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pkgmodel
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrewkroh/go-package-spec/pkgspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	const (
+		logsFields      = "testdata/integration/data_stream/logs/fields/fields.yml"
+		logsBaseFields  = "testdata/integration/data_stream/logs/fields/base-fields.yml"
+		transformFields = "testdata/integration/elasticsearch/transform/latest/fields/fields.yml"
+		inputFields     = "testdata/input/fields/input.yml"
+		looseFields     = "testdata/loose/fields.yml"
+	)
+
+	m, err := Load(context.Background(), []string{logsFields, transformFields, inputFields, looseFields})
+	require.NoError(t, err)
+	require.Len(t, m.Packages, 2)
+
+	input, integration := m.Packages[0], m.Packages[1]
+
+	// Integration package.
+	assert.Equal(t, filepath.FromSlash("testdata/integration"), integration.Root)
+	assert.Equal(t, "acme", integration.Manifest.Name)
+	assert.Equal(t, "integration", integration.Manifest.Type)
+	assert.Equal(t, "3.0.0", integration.Manifest.FormatVersion)
+	assert.Equal(t, "^8.13.0", integration.Manifest.KibanaVersion())
+	require.NotNil(t, integration.BuildManifest)
+	assert.Equal(t, "git@v8.11.0", integration.BuildManifest.Dependencies.ECS.Reference)
+	assert.Empty(t, integration.FieldsFiles)
+
+	require.Len(t, integration.DataStreams, 1)
+	ds := integration.DataStreams[0]
+	assert.Equal(t, "logs", ds.Name)
+	assert.Equal(t, "logs", ds.Manifest.Type)
+	assert.Equal(t, "acme.logs", ds.Dataset())
+	assert.Equal(t, "logsdb", ds.Manifest.Elasticsearch.IndexMode)
+	assert.Equal(t, 5000, ds.Manifest.Elasticsearch.IndexTemplate.Settings["index.mapping.total_fields.limit"])
+	// All fields files of the data stream are discovered, not only the
+	// ones that were requested.
+	assert.Equal(t, []string{filepath.FromSlash(logsBaseFields), filepath.FromSlash(logsFields)}, ds.FieldsFiles)
+
+	require.Len(t, integration.Transforms, 1)
+	assert.Equal(t, "latest", integration.Transforms[0].Name)
+	assert.Equal(t, []string{filepath.FromSlash(transformFields)}, integration.Transforms[0].FieldsFiles)
+
+	// Input package.
+	assert.Equal(t, "input", input.Manifest.Type)
+	assert.Nil(t, input.BuildManifest)
+	assert.Empty(t, input.DataStreams)
+	assert.Equal(t, []string{filepath.FromSlash(inputFields)}, input.FieldsFiles)
+
+	// Ownership.
+	owner, found := m.Owner(logsBaseFields)
+	require.True(t, found)
+	assert.Same(t, ds, owner.DataStream)
+	assert.Equal(t, ds.Dir, owner.Dir())
+	assert.Equal(t, ds.FieldsFiles, owner.FieldsFiles())
+	assert.Equal(t, "data stream logs", owner.Description())
+
+	owner, found = m.Owner(transformFields)
+	require.True(t, found)
+	assert.Same(t, integration.Transforms[0], owner.Transform)
+	assert.Nil(t, owner.DataStream)
+
+	owner, found = m.Owner(inputFields)
+	require.True(t, found)
+	assert.Same(t, input, owner.Package)
+	assert.Equal(t, input.Root, owner.Dir())
+
+	_, found = m.Owner(looseFields)
+	assert.False(t, found)
+}
+
+func TestLoadMalformed(t *testing.T) {
+	const (
+		brokenFields = "testdata/broken/data_stream/logs/fields/fields.yml"
+		inputFields  = "testdata/input/fields/input.yml"
+	)
+
+	// Errors in one package do not prevent loading the others.
+	m, err := Load(context.Background(), []string{brokenFields, inputFields})
+	require.NoError(t, err)
+	require.Len(t, m.Packages, 2)
+
+	broken := m.Packages[0]
+	assert.Equal(t, filepath.FromSlash("testdata/broken"), broken.Root)
+	assert.ErrorContains(t, broken.ManifestErr, "failed to unmarshal")
+	assert.Equal(t, "broken", broken.Name())
+	assert.Nil(t, broken.BuildManifest)
+	assert.ErrorContains(t, broken.BuildManifestErr, "failed to unmarshal")
+	require.Len(t, broken.DataStreams, 1)
+	assert.ErrorContains(t, broken.DataStreams[0].ManifestErr, "failed to unmarshal")
+
+	owner, found := m.Owner(brokenFields)
+	require.True(t, found)
+	assert.Same(t, broken.DataStreams[0], owner.DataStream)
+
+	input := m.Packages[1]
+	assert.NoError(t, input.ManifestErr)
+	assert.NoError(t, input.BuildManifestErr)
+}
+
+func TestLoadCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Load(ctx, []string{"testdata/input/fields/input.yml"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestForEachOwner(t *testing.T) {
+	const (
+		logsFields  = "testdata/integration/data_stream/logs/fields/fields.yml"
+		inputFields = "testdata/input/fields/input.yml"
+		looseFields = "testdata/loose/fields.yml"
+	)
+
+	m, err := Load(context.Background(), []string{logsFields, inputFields, looseFields})
+	require.NoError(t, err)
+
+	var fields []*pkgspec.Field
+	for _, path := range []string{logsFields, looseFields, inputFields, logsFields} {
+		f := []pkgspec.Field{{Name: "foo"}}
+		pkgspec.AnnotateFileMetadata(path, &f)
+		fields = append(fields, &f[0])
+	}
+
+	var owners []string
+	err = m.ForEachOwner(context.Background(), fields, func(o Owner, fields []*pkgspec.Field) error {
+		owners = append(owners, o.Description())
+		assert.NotEmpty(t, fields)
+		return nil
+	})
+	require.NoError(t, err)
+	// Sorted by directory, with the loose fields file skipped.
+	assert.Equal(t, []string{"package acme_input", "data stream logs"}, owners)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = m.ForEachOwner(ctx, fields, func(Owner, []*pkgspec.Field) error {
+		t.Fatal("called after cancel")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,97 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// Checkstyle writes the diagnostics as a Checkstyle XML report. Diagnostics
+// are grouped by file in the order in which each file first appears.
+func Checkstyle(diags []analysis.Diagnostic, w io.Writer) error {
+	r := checkstyleReport{Version: "4.3"}
+
+	fileIndex := map[string]int{}
+	for _, d := range diags {
+		path := relPath(d.Pos.File)
+		idx, found := fileIndex[path]
+		if !found {
+			idx = len(r.Files)
+			fileIndex[path] = idx
+			r.Files = append(r.Files, checkstyleFile{Name: path})
+		}
+
+		r.Files[idx].Errors = append(r.Files[idx].Errors, checkstyleError{
+			Line:     d.Pos.Line,
+			Column:   d.Pos.Col,
+			Severity: "warning",
+			Message:  checkstyleMessage(d),
+			Source:   "fydler." + d.Category,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// checkstyleMessage appends related information to the message because
+// Checkstyle has no concept of related locations.
+func checkstyleMessage(d analysis.Diagnostic) string {
+	if len(d.Related) == 0 {
+		return d.Message
+	}
+
+	var sb strings.Builder
+	sb.WriteString(d.Message)
+	for _, r := range d.Related {
+		fmt.Fprintf(&sb, "\n%s:%d %s", relPath(r.Pos.File), r.Pos.Line, r.Message)
+	}
+	return sb.String()
+}
@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+// codeQualityIssue is an issue in the GitLab Code Quality report format.
+// See https://docs.gitlab.com/ci/testing/code_quality/#code-quality-report-format.
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines codeQualityLines `json:"lines"`
+}
+
+type codeQualityLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end,omitempty"`
+}
+
+// GitLabCodeQuality writes the diagnostics as a GitLab Code Quality report.
+func GitLabCodeQuality(diags []analysis.Diagnostic, w io.Writer) error {
+	fingerprints := make([]string, len(diags))
+	counts := map[string]int{}
+	for i, d := range diags {
+		fingerprints[i] = Fingerprint(d)
+		counts[fingerprints[i]]++
+	}
+
+	issues := make([]codeQualityIssue, 0, len(diags))
+	seen := map[string]int{}
+	for i, d := range diags {
+		// GitLab requires unique fingerprints, but one field can be reported
+		// more than once in a file by the same analyzer. Every occurrence of
+		// a repeated fingerprint includes the message so that the result
+		// does not depend on the order of the diagnostics. Only identical
+		// diagnostics are numbered.
+		fp := fingerprints[i]
+		if counts[fp] > 1 {
+			fp = hashStrings(fp, d.Message)
+		}
+		n := seen[fp]
+		seen[fp]++
+		if n > 0 {
+			fp = fmt.Sprintf("%s-%d", fp, n)
+		}
+
+		issues = append(issues, codeQualityIssue{
+			Description: d.Message,
+			CheckName:   d.Category,
+			Fingerprint: fp,
+			Severity:    "minor",
+			Location: codeQualityLocation{
+				Path:  relPath(d.Pos.File),
+				Lines: codeQualityLines{Begin: max(d.Pos.Line, 1), End: endLine(d)},
+			},
+		})
+	}
+
+	return writeJSON(w, issues)
+}
+
+// endLine returns the last line of a multi-line diagnostic, or zero.
+func endLine(d analysis.Diagnostic) int {
+	if d.End.Line > d.Pos.Line {
+		return d.End.Line
+	}
+	return 0
+}
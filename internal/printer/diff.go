@@ -0,0 +1,193 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+// ReportDiff is the difference between two reports. Diagnostics are matched
+// by Fingerprint so that changes in line numbers do not affect the result.
+type ReportDiff struct {
+	Added     []analysis.Diagnostic `json:"added"`
+	Removed   []analysis.Diagnostic `json:"removed"`
+	Unchanged []analysis.Diagnostic `json:"unchanged"`
+	Analyzers []ReportDiffCount     `json:"analyzers"`
+}
+
+// ReportDiffCount is the number of changes for a single analyzer.
+type ReportDiffCount struct {
+	Name      string `json:"name"`
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
+	Unchanged int    `json:"unchanged"`
+}
+
+// DiffReports compares the diagnostics of two reports. When the same
+// fingerprint occurs multiple times, occurrences are paired in order and any
+// excess is considered added or removed. Unchanged diagnostics are taken from
+// the new report.
+func DiffReports(oldReport, newReport *Report) *ReportDiff {
+	oldByFingerprint := map[string][]analysis.Diagnostic{}
+	for _, d := range oldReport.Diags {
+		fp := Fingerprint(d)
+		oldByFingerprint[fp] = append(oldByFingerprint[fp], d)
+	}
+
+	diff := &ReportDiff{
+		Added:     []analysis.Diagnostic{},
+		Removed:   []analysis.Diagnostic{},
+		Unchanged: []analysis.Diagnostic{},
+	}
+	for _, d := range newReport.Diags {
+		fp := Fingerprint(d)
+		if matches := oldByFingerprint[fp]; len(matches) > 0 {
+			oldByFingerprint[fp] = matches[1:]
+			diff.Unchanged = append(diff.Unchanged, d)
+			continue
+		}
+		diff.Added = append(diff.Added, d)
+	}
+	// Iterate over the old report to preserve its order.
+	for _, d := range oldReport.Diags {
+		fp := Fingerprint(d)
+		if matches := oldByFingerprint[fp]; len(matches) > 0 {
+			oldByFingerprint[fp] = matches[1:]
+			diff.Removed = append(diff.Removed, d)
+		}
+	}
+
+	slices.SortFunc(diff.Added, compareDiagnostic)
+	slices.SortFunc(diff.Removed, compareDiagnostic)
+	slices.SortFunc(diff.Unchanged, compareDiagnostic)
+
+	counts := map[string]*ReportDiffCount{}
+	count := func(diags []analysis.Diagnostic, incr func(*ReportDiffCount)) {
+		for _, d := range diags {
+			incr(getOrCreate(counts, d.Category, func() *ReportDiffCount {
+				return &ReportDiffCount{Name: d.Category}
+			}))
+		}
+	}
+	count(diff.Added, func(c *ReportDiffCount) { c.Added++ })
+	count(diff.Removed, func(c *ReportDiffCount) { c.Removed++ })
+	count(diff.Unchanged, func(c *ReportDiffCount) { c.Unchanged++ })
+
+	diff.Analyzers = make([]ReportDiffCount, 0, len(counts))
+	for _, c := range counts {
+		diff.Analyzers = append(diff.Analyzers, *c)
+	}
+	slices.SortFunc(diff.Analyzers, func(a, b ReportDiffCount) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return diff
+}
+
+// DiffText writes the report difference as text.
+func DiffText(diff *ReportDiff, w io.Writer) error {
+	fmt.Fprintf(w, "%d added, %d removed, %d unchanged\n", len(diff.Added), len(diff.Removed), len(diff.Unchanged))
+
+	if len(diff.Analyzers) > 0 {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ANALYZER\tADDED\tREMOVED\tUNCHANGED")
+		for _, c := range diff.Analyzers {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", c.Name, c.Added, c.Removed, c.Unchanged)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	for _, section := range []struct {
+		title string
+		sign  string
+		diags []analysis.Diagnostic
+	}{
+		{"Added", "+", diff.Added},
+		{"Removed", "-", diff.Removed},
+	} {
+		if len(section.diags) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		for _, d := range section.diags {
+			fmt.Fprintf(w, "%s %s %s (%s)\n", section.sign, d.Pos, d.Message, d.Category)
+		}
+	}
+	return nil
+}
+
+// DiffMarkdown writes the report difference as markdown suitable for a pull
+// request comment.
+func DiffMarkdown(diff *ReportDiff, w io.Writer, version string) error {
+	fmt.Fprintf(w, "## fydler report diff\n\n")
+	fmt.Fprintf(w, "%s, %s, and %d unchanged.\n\n",
+		pluralize(len(diff.Added), "new finding", "new findings"),
+		pluralize(len(diff.Removed), "fixed finding", "fixed findings"),
+		len(diff.Unchanged))
+
+	if len(diff.Analyzers) > 0 {
+		fmt.Fprintln(w, "| Analyzer | Added | Removed | Unchanged |")
+		fmt.Fprintln(w, "|----------|------:|--------:|----------:|")
+		for _, c := range diff.Analyzers {
+			fmt.Fprintf(w, "| %s | %d | %d | %d |\n", c.Name, c.Added, c.Removed, c.Unchanged)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(diff.Added) > 0 {
+		fmt.Fprintf(w, "### Added\n\n")
+		for _, d := range diff.Added {
+			fmt.Fprintf(w, "- %s %s (`%s`)\n", markdownRange(d.Pos, d.End), escapeMarkdown(d.Message), d.Category)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(diff.Removed) > 0 {
+		// Removed findings are not linked because the lines may no longer
+		// exist in the new revision.
+		fmt.Fprintf(w, "### Removed\n\n")
+		for _, d := range diff.Removed {
+			fmt.Fprintf(w, "- %s:%d %s (`%s`)\n", relPath(d.Pos.File), d.Pos.Line, escapeMarkdown(d.Message), d.Category)
+		}
+		fmt.Fprintln(w)
+	}
+
+	_, err := fmt.Fprintf(w, "--------\nGenerated by [fydler](https://github.com/andrewkroh/fydler) %s\n", version)
+	return err
+}
+
+// DiffJSON writes the report difference as JSON.
+func DiffJSON(diff *ReportDiff, w io.Writer) error {
+	return writeJSON(w, diff)
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
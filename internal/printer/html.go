@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"cmp"
+	_ "embed"
+	"html/template"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+//go:embed html.tmpl
+var htmlTemplateText string
+
+var htmlTemplate = template.Must(template.New("html").Parse(htmlTemplateText))
+
+// snippetContext is the number of lines shown before and after the line
+// referenced by a diagnostic.
+const snippetContext = 2
+
+type htmlReport struct {
+	Version   string
+	Timestamp string
+	Analyzers []htmlCount
+	Packages  []htmlCount
+	Diags     []htmlDiagnostic
+}
+
+type htmlCount struct {
+	Name        string
+	Description string
+	CanFix      bool
+	Count       int
+}
+
+type htmlDiagnostic struct {
+	Category string
+	Package  string
+	File     string
+	Line     int
+	Col      int
+	URL      string
+	Message  string
+	Snippet  []sourceLine
+	Related  []htmlRelated
+}
+
+type htmlRelated struct {
+	File    string
+	Line    int
+	URL     string
+	Message string
+	Snippet []sourceLine
+}
+
+// HTML writes a self-contained HTML report. The report contains summary
+// counts per analyzer and per package, a sortable and filterable table of
+// the diagnostics, and the source surrounding each reported position.
+func HTML(diags []analysis.Diagnostic, w io.Writer, analyzers []*analysis.Analyzer, version string) error {
+	diags = slices.Clone(diags)
+	slices.SortFunc(diags, compareDiagnostic)
+
+	r := htmlReport{
+		Version:   version,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Diags:     make([]htmlDiagnostic, 0, len(diags)),
+	}
+
+	analyzerCounts := map[string]int{}
+	packageCounts := map[string]int{}
+	sources := sourceCache{}
+	for _, d := range diags {
+		pkg := packageName(d.Pos.File)
+		analyzerCounts[d.Category]++
+		packageCounts[pkg]++
+
+		hd := htmlDiagnostic{
+			Category: d.Category,
+			Package:  pkg,
+			File:     relPath(d.Pos.File),
+			Line:     d.Pos.Line,
+			Col:      d.Pos.Col,
+			URL:      rangeURL(d.Pos, d.End),
+			Message:  d.Message,
+			Snippet:  sources.snippet(d.Pos, snippetContext),
+		}
+		for _, rel := range d.Related {
+			hd.Related = append(hd.Related, htmlRelated{
+				File:    relPath(rel.Pos.File),
+				Line:    rel.Pos.Line,
+				URL:     sourceURL(rel.Pos),
+				Message: rel.Message,
+				Snippet: sources.snippet(rel.Pos, snippetContext),
+			})
+		}
+		r.Diags = append(r.Diags, hd)
+	}
+
+	for _, a := range analyzers {
+		r.Analyzers = append(r.Analyzers, htmlCount{
+			Name:        a.Name,
+			Description: a.Description,
+			CanFix:      a.CanFix,
+			Count:       analyzerCounts[a.Name],
+		})
+		delete(analyzerCounts, a.Name)
+	}
+	// Include categories that were not produced by a known analyzer.
+	for name, count := range analyzerCounts {
+		r.Analyzers = append(r.Analyzers, htmlCount{Name: name, Count: count})
+	}
+	slices.SortFunc(r.Analyzers, compareCount)
+
+	for name, count := range packageCounts {
+		r.Packages = append(r.Packages, htmlCount{Name: name, Count: count})
+	}
+	slices.SortFunc(r.Packages, compareCount)
+
+	return htmlTemplate.Execute(w, r)
+}
+
+// compareCount orders by descending count and then by name.
+func compareCount(a, b htmlCount) int {
+	if c := cmp.Compare(b.Count, a.Count); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Name, b.Name)
+}
+
+// compareDiagnostic orders diagnostics by category, file, and line.
+func compareDiagnostic(a, b analysis.Diagnostic) int {
+	if c := cmp.Compare(a.Category, b.Category); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Pos.File, b.Pos.File); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Pos.Line, b.Pos.Line)
+}
@@ -0,0 +1,289 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+// linkTemplate contains the URL templates for a source code hosting provider.
+// The templates may contain the placeholders {base_url}, {repo}, {ref},
+// {path}, {line}, and {end_line}.
+type linkTemplate struct {
+	BaseURL string // Default base URL. Empty if the provider has no default.
+	Line    string // Template for a link to a single line.
+	Range   string // Template for a link to a range of lines.
+}
+
+// linkTemplates contains the URL templates for each supported provider.
+var linkTemplates = map[string]linkTemplate{
+	"github": {
+		BaseURL: "https://github.com",
+		Line:    "{base_url}/{repo}/blob/{ref}/{path}#L{line}",
+		Range:   "{base_url}/{repo}/blob/{ref}/{path}#L{line}-L{end_line}",
+	},
+	// GitHub Enterprise Server uses the GitHub URL format on a custom host.
+	"ghes": {
+		Line:  "{base_url}/{repo}/blob/{ref}/{path}#L{line}",
+		Range: "{base_url}/{repo}/blob/{ref}/{path}#L{line}-L{end_line}",
+	},
+	"gitlab": {
+		BaseURL: "https://gitlab.com",
+		Line:    "{base_url}/{repo}/-/blob/{ref}/{path}#L{line}",
+		Range:   "{base_url}/{repo}/-/blob/{ref}/{path}#L{line}-{end_line}",
+	},
+	"bitbucket": {
+		BaseURL: "https://bitbucket.org",
+		Line:    "{base_url}/{repo}/src/{ref}/{path}#lines-{line}",
+		Range:   "{base_url}/{repo}/src/{ref}/{path}#lines-{line}:{end_line}",
+	},
+}
+
+// LinkProviders returns the names of the supported link providers.
+func LinkProviders() []string {
+	names := make([]string, 0, len(linkTemplates))
+	for name := range linkTemplates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// LinkBuilder builds URLs that link to locations in the source repository
+// and relativizes paths to the repository root.
+type LinkBuilder struct {
+	Provider string // Provider name (see LinkProviders).
+	BaseURL  string // Base URL of the provider (e.g. https://github.example.com).
+	Repo     string // Repository path (e.g. elastic/integrations).
+	Ref      string // Commit SHA, branch, or tag.
+	Root     string // Local path of the repository root to trim from file paths.
+	Template string // Custom URL template that overrides the provider templates.
+}
+
+// LinkBuilderFromEnv returns a LinkBuilder configured from the environment
+// variables set by GitHub Actions, GitLab CI, or Bitbucket Pipelines. The
+// FYDLER_LINK_* environment variables take precedence. If the repository
+// cannot be determined, it defaults to elastic/integrations on GitHub.
+func LinkBuilderFromEnv() *LinkBuilder {
+	return NewLinkBuilder(LinkBuilder{})
+}
+
+// NewLinkBuilder returns a LinkBuilder configured from the environment (see
+// LinkBuilderFromEnv) where any non-empty value in overrides takes
+// precedence. The values detected from the CI environment are only used when
+// the provider is not changed to a different one by FYDLER_LINK_PROVIDER or
+// overrides.
+func NewLinkBuilder(overrides LinkBuilder) *LinkBuilder {
+	b := ciLinkBuilder()
+
+	provider := overrides.Provider
+	if provider == "" {
+		provider = os.Getenv("FYDLER_LINK_PROVIDER")
+	}
+	if provider != "" && provider != b.Provider {
+		b = LinkBuilder{Provider: provider}
+	}
+
+	for _, o := range []struct {
+		env      string
+		override string
+		field    *string
+	}{
+		{"FYDLER_LINK_BASE_URL", overrides.BaseURL, &b.BaseURL},
+		{"FYDLER_LINK_REPO", overrides.Repo, &b.Repo},
+		{"FYDLER_LINK_REF", overrides.Ref, &b.Ref},
+		{"FYDLER_LINK_ROOT", overrides.Root, &b.Root},
+		{"FYDLER_LINK_TEMPLATE", overrides.Template, &b.Template},
+	} {
+		if v := os.Getenv(o.env); v != "" {
+			*o.field = v
+		}
+		if o.override != "" {
+			*o.field = o.override
+		}
+	}
+
+	if b.Repo == "" && b.Provider == "github" {
+		b.Repo = "elastic/integrations"
+	}
+	if b.Ref == "" {
+		b.Ref = "main"
+	}
+	return &b
+}
+
+// ciLinkBuilder returns a LinkBuilder configured from the environment
+// variables set by GitHub Actions, GitLab CI, or Bitbucket Pipelines.
+func ciLinkBuilder() LinkBuilder {
+	switch {
+	case os.Getenv("GITLAB_CI") != "":
+		return LinkBuilder{
+			Provider: "gitlab",
+			BaseURL:  os.Getenv("CI_SERVER_URL"),
+			Repo:     os.Getenv("CI_PROJECT_PATH"),
+			Ref:      os.Getenv("CI_COMMIT_SHA"),
+			Root:     os.Getenv("CI_PROJECT_DIR"),
+		}
+	case os.Getenv("BITBUCKET_BUILD_NUMBER") != "":
+		return LinkBuilder{
+			Provider: "bitbucket",
+			Repo:     os.Getenv("BITBUCKET_REPO_FULL_NAME"),
+			Ref:      os.Getenv("BITBUCKET_COMMIT"),
+			Root:     os.Getenv("BITBUCKET_CLONE_DIR"),
+		}
+	default:
+		// These are set by GitHub Actions. GitHub Enterprise Server uses
+		// the same variables with its own server URL.
+		provider := "github"
+		if u := os.Getenv("GITHUB_SERVER_URL"); u != "" && u != linkTemplates["github"].BaseURL {
+			provider = "ghes"
+		}
+		return LinkBuilder{
+			Provider: provider,
+			BaseURL:  os.Getenv("GITHUB_SERVER_URL"),
+			Repo:     os.Getenv("GITHUB_REPOSITORY"),
+			Ref:      os.Getenv("GITHUB_SHA"),
+			Root:     os.Getenv("GITHUB_WORKSPACE"),
+		}
+	}
+}
+
+// Validate returns an error if the configuration is invalid.
+func (b *LinkBuilder) Validate() error {
+	if b.Template != "" {
+		return nil
+	}
+	t, found := linkTemplates[b.Provider]
+	if !found {
+		return fmt.Errorf("invalid link provider %q (allowed providers are %s)",
+			b.Provider, strings.Join(LinkProviders(), ", "))
+	}
+	if t.BaseURL == "" && b.BaseURL == "" {
+		return fmt.Errorf("link provider %q requires a base URL", b.Provider)
+	}
+	if b.Repo == "" {
+		return errors.New("link repository must be specified")
+	}
+	return nil
+}
+
+// RelPath returns the path relative to the repository root (if set) using
+// forward slashes as the separator.
+func (b *LinkBuilder) RelPath(p string) string {
+	if b.Root != "" {
+		if rel, err := filepath.Rel(b.Root, p); err == nil {
+			p = rel
+		}
+	}
+	return filepath.ToSlash(p)
+}
+
+// URL returns a URL that links to the lines from line through endLine of the
+// file. If endLine is not after line, then the URL links to a single line. It
+// returns an empty string if no URL can be built.
+func (b *LinkBuilder) URL(path string, line, endLine int) string {
+	tmpl := b.Template
+	baseURL := b.BaseURL
+	if tmpl == "" {
+		t, found := linkTemplates[b.Provider]
+		if !found || b.Repo == "" {
+			return ""
+		}
+		tmpl = t.Line
+		if endLine > line {
+			tmpl = t.Range
+		}
+		if baseURL == "" {
+			baseURL = t.BaseURL
+		}
+	}
+	if endLine < line {
+		endLine = line
+	}
+
+	return strings.NewReplacer(
+		"{base_url}", strings.TrimSuffix(baseURL, "/"),
+		"{repo}", b.Repo,
+		"{ref}", b.Ref,
+		"{path}", b.RelPath(path),
+		"{line}", strconv.Itoa(line),
+		"{end_line}", strconv.Itoa(endLine),
+	).Replace(tmpl)
+}
+
+// linkBuilder is the LinkBuilder used by the printers. When nil, it is
+// created from the environment on first use.
+var linkBuilder *LinkBuilder
+
+// SetLinkBuilder sets the LinkBuilder used by all printers to create links and
+// relative paths.
+func SetLinkBuilder(b *LinkBuilder) {
+	linkBuilder = b
+}
+
+func links() *LinkBuilder {
+	if linkBuilder == nil {
+		linkBuilder = LinkBuilderFromEnv()
+	}
+	return linkBuilder
+}
+
+// sourceURL returns a URL that links to the position. It returns an empty
+// string if no URL can be built.
+func sourceURL(p analysis.Pos) string {
+	return links().URL(p.File, p.Line, 0)
+}
+
+// rangeURL returns a URL that links to the lines from start through end. A
+// zero end links to the start line only.
+func rangeURL(start, end analysis.Pos) string {
+	return links().URL(start.File, start.Line, end.Line)
+}
+
+// relPath returns the path relative to the repository root using forward
+// slashes as the separator.
+func relPath(p string) string {
+	return links().RelPath(p)
+}
+
+// markdownLocation returns a markdown link to the position. The location is
+// not linked if no URL can be built.
+func markdownLocation(p analysis.Pos) string {
+	return markdownRange(p, analysis.Pos{})
+}
+
+// markdownRange returns a markdown link to the lines from start through end.
+// The location is not linked if no URL can be built.
+func markdownRange(start, end analysis.Pos) string {
+	loc := relPath(start.File) + ":" + strconv.Itoa(start.Line)
+	if end.Line > start.Line {
+		loc += "-" + strconv.Itoa(end.Line)
+	}
+	if u := rangeURL(start, end); u != "" {
+		return "[" + loc + "](" + u + ")"
+	}
+	return loc
+}
@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+// unowned is the name used for diagnostics that have no code owner.
+const unowned = "(unowned)"
+
+// diagnosticOwners returns the owners of the diagnostic, or unowned.
+func diagnosticOwners(d analysis.Diagnostic) []string {
+	if len(d.Owners) == 0 {
+		return []string{unowned}
+	}
+	return d.Owners
+}
+
+// groupByOwner groups diagnostics by code owner. A diagnostic with multiple
+// owners is included in the group of each owner. Groups are sorted by name
+// with the unowned group last.
+func groupByOwner(diags []analysis.Diagnostic) []DiagnosticGroup {
+	index := map[string]int{}
+	var groups []DiagnosticGroup
+	for _, d := range diags {
+		for _, owner := range diagnosticOwners(d) {
+			i, found := index[owner]
+			if !found {
+				i = len(groups)
+				index[owner] = i
+				groups = append(groups, DiagnosticGroup{Name: owner})
+			}
+			groups[i].Diags = append(groups[i].Diags, d)
+		}
+	}
+	slices.SortStableFunc(groups, func(a, b DiagnosticGroup) int {
+		return compareOwner(a.Name, b.Name)
+	})
+	return groups
+}
+
+// compareOwner orders owners by name with the unowned group last.
+func compareOwner(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == unowned:
+		return 1
+	case b == unowned:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,263 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+// maxFrameLines is the maximum number of source lines shown in a code frame.
+const maxFrameLines = 6
+
+// Pretty writes the diagnostics as code frames, similar to the output of the
+// Rust compiler. Each frame shows the offending lines of the source file with
+// a caret under the reported column, or carets under the reported range when
+// the diagnostic has an end position.
+func Pretty(diags []analysis.Diagnostic, w io.Writer) error {
+	return pretty(diags, w, false)
+}
+
+// ColorPretty writes the diagnostics like Pretty but with color, regardless
+// of whether color has been disabled for the process (see color.NoColor).
+func ColorPretty(diags []analysis.Diagnostic, w io.Writer) error {
+	return pretty(diags, w, true)
+}
+
+func pretty(diags []analysis.Diagnostic, w io.Writer, wantColor bool) error {
+	p := &prettyPrinter{
+		w:       w,
+		sources: sourceCache{},
+		fields:  map[string]map[[2]int]fieldSummary{},
+		header:  color.New(color.FgRed, color.Bold),
+		gutter:  color.New(color.FgBlue, color.Bold),
+		caret:   color.New(color.FgRed, color.Bold),
+		note:    color.New(color.FgCyan, color.Bold),
+	}
+	for _, c := range []*color.Color{p.header, p.gutter, p.caret, p.note} {
+		if wantColor {
+			c.EnableColor()
+		} else {
+			c.DisableColor()
+		}
+	}
+
+	for _, d := range diags {
+		if err := p.print(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type prettyPrinter struct {
+	w       io.Writer
+	sources sourceCache
+	fields  map[string]map[[2]int]fieldSummary // File path to field line and column.
+
+	header, gutter, caret, note *color.Color
+}
+
+// fieldSummary is the name and type of a field declaration.
+type fieldSummary struct {
+	Name string
+	Type string
+}
+
+func (s fieldSummary) String() string {
+	switch {
+	case s.Name == "":
+		return ""
+	case s.Type == "":
+		return s.Name
+	default:
+		return s.Name + " (" + s.Type + ")"
+	}
+}
+
+func (p *prettyPrinter) print(d analysis.Diagnostic) error {
+	if _, err := p.header.Fprint(p.w, d.Category); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(p.w, ": %s\n", d.Message); err != nil {
+		return err
+	}
+	if err := p.frame(d.Pos, d.End, p.field(d.Pos).String()); err != nil {
+		return err
+	}
+
+	for _, r := range d.Related {
+		if _, err := p.note.Fprint(p.w, "note"); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(p.w, ": %s\n", r.Message); err != nil {
+			return err
+		}
+		if err := p.frame(r.Pos, analysis.Pos{}, p.field(r.Pos).String()); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(p.w)
+	return err
+}
+
+// frame writes the location and source lines for the position. The label is
+// written after the caret. A non-zero end limits the frame to the lines of
+// the range.
+func (p *prettyPrinter) frame(pos, end analysis.Pos, label string) error {
+	lines := p.frameLines(pos, end)
+
+	width := len(strconv.Itoa(pos.Line))
+	if len(lines) > 0 {
+		width = len(strconv.Itoa(lines[len(lines)-1].Number))
+	}
+	pad := strings.Repeat(" ", width)
+
+	if _, err := p.gutter.Fprintf(p.w, "%s--> ", pad); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(p.w, relPath(pos.File)+strings.TrimPrefix(pos.String(), pos.File)); err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if _, err := p.gutter.Fprintf(p.w, "%s |\n", pad); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if _, err := p.gutter.Fprintf(p.w, "%*d | ", width, l.Number); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(p.w, l.Text); err != nil {
+			return err
+		}
+		if !l.Target {
+			continue
+		}
+
+		col := pos.Col
+		if col == 0 {
+			// Point at the first non-whitespace character.
+			col = len(l.Text) - len(strings.TrimLeft(l.Text, " \t")) + 1
+		}
+		carets := 1
+		if end.Line == pos.Line && end.Col > col {
+			carets = end.Col - col
+		}
+		if _, err := p.gutter.Fprintf(p.w, "%s | ", pad); err != nil {
+			return err
+		}
+		if _, err := p.caret.Fprint(p.w, strings.TrimRight(strings.Repeat(" ", col-1)+strings.Repeat("^", carets)+" "+label, " ")); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(p.w); err != nil {
+			return err
+		}
+	}
+	_, err := p.gutter.Fprintf(p.w, "%s |\n", pad)
+	return err
+}
+
+// frameLines returns the line referenced by the position followed by the
+// lines that are indented deeper than the position's column (i.e. the rest
+// of the YAML mapping that starts at the position). When end is set, the
+// lines through the end of the range are returned.
+func (p *prettyPrinter) frameLines(pos, end analysis.Pos) []sourceLine {
+	lines := p.sources.snippet(pos, maxFrameLines-1)
+	for i, l := range lines {
+		if l.Target {
+			lines = lines[i:]
+			break
+		}
+	}
+	if end.Line >= pos.Line && len(lines) > 0 {
+		return lines[:min(len(lines), end.Line-pos.Line+1)]
+	}
+	if pos.Col == 0 || len(lines) == 0 {
+		return lines[:min(len(lines), 1)]
+	}
+
+	n := 1
+	for ; n < len(lines); n++ {
+		text := lines[n].Text
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		if strings.TrimSpace(text) == "" || indent < pos.Col-1 {
+			break
+		}
+	}
+	return lines[:n]
+}
+
+// field returns the name and type of the field declared at the position, or
+// of the field containing the attribute at the position. It returns an empty
+// fieldSummary if the position does not refer to a field in a YAML file.
+func (p *prettyPrinter) field(pos analysis.Pos) fieldSummary {
+	fields, found := p.fields[pos.File]
+	if !found {
+		fields = map[[2]int]fieldSummary{}
+		p.fields[pos.File] = fields
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(strings.Join(p.sources.lines(pos.File), "\n")), &doc); err == nil {
+			collectFieldSummaries(&doc, fields)
+		}
+	}
+	// The keys of a field's mapping share its column so search upward for
+	// the start of the mapping that contains the position.
+	for line := pos.Line; line > 0; line-- {
+		if s, found := fields[[2]int{line, pos.Col}]; found {
+			return s
+		}
+	}
+	return fieldSummary{}
+}
+
+// collectFieldSummaries records the name and type of every mapping node that
+// contains a 'name' key.
+func collectFieldSummaries(n *yaml.Node, out map[[2]int]fieldSummary) {
+	if n.Kind == yaml.MappingNode {
+		var s fieldSummary
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			switch n.Content[i].Value {
+			case "name":
+				s.Name = n.Content[i+1].Value
+			case "type":
+				s.Type = n.Content[i+1].Value
+			case "external":
+				if s.Type == "" {
+					s.Type = "external: " + n.Content[i+1].Value
+				}
+			}
+		}
+		if s.Name != "" {
+			out[[2]int{n.Line, n.Column}] = s
+		}
+	}
+	for _, c := range n.Content {
+		collectFieldSummaries(c, out)
+	}
+}
@@ -19,38 +19,144 @@
 package printer
 
 import (
-	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/fatih/color"
 
 	"github.com/andrewkroh/fydler/internal/analysis"
 )
 
-func JSON(diags []analysis.Diagnostic, w io.Writer) error {
-	type Report struct {
-		Diags []analysis.Diagnostic `json:"diagnostics"`
-		Time  string                `json:"timestamp"`
-		Args  []string              `json:"args,omitempty"`
-	}
+// Report is the JSON report format.
+type Report struct {
+	Diags     []analysis.Diagnostic `json:"diagnostics"`
+	Time      string                `json:"timestamp"`
+	Args      []string              `json:"args,omitempty"`
+	Version   string                `json:"version,omitempty"`
+	Analyzers []ReportAnalyzer      `json:"analyzers,omitempty"`
+}
 
+// ReportAnalyzer describes an analyzer that was run to produce a Report.
+type ReportAnalyzer struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CanFix      bool   `json:"can_fix,omitempty"`
+}
+
+// JSON writes the diagnostics as a JSON Report. File paths in the report are
+// relative to the repository root (see reportPaths) so that reports created
+// from different checkouts can be compared. The args are the command
+// line arguments used to invoke fydler.
+func JSON(diags []analysis.Diagnostic, w io.Writer, analyzers []*analysis.Analyzer, version string, args []string) error {
 	r := Report{
-		Diags: diags,
-		Time:  time.Now().Format(time.RFC3339),
+		Diags:     reportPaths{}.relativize(diags),
+		Time:      time.Now().Format(time.RFC3339),
+		Args:      args,
+		Version:   strings.TrimSpace(version),
+		Analyzers: make([]ReportAnalyzer, 0, len(analyzers)),
+	}
+	for _, a := range analyzers {
+		r.Analyzers = append(r.Analyzers, ReportAnalyzer{
+			Name:        a.Name,
+			Description: a.Description,
+			CanFix:      a.CanFix,
+		})
+	}
+
+	return writeJSON(w, r)
+}
+
+// reportPaths makes file paths relative to the repository root. The root is
+// the configured link root or, if unset, the nearest ancestor directory of the
+// file that contains .git. Paths of files that do not exist are only
+// relativized to the configured link root. It caches the root of each
+// directory.
+type reportPaths map[string]string
+
+// relativize returns a copy of the diagnostics with relative file paths.
+func (rp reportPaths) relativize(diags []analysis.Diagnostic) []analysis.Diagnostic {
+	out := make([]analysis.Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		d.Pos.File = rp.rel(d.Pos.File)
+		d.End.File = rp.rel(d.End.File)
+		d.Related = slices.Clone(d.Related)
+		for i := range d.Related {
+			d.Related[i].Pos.File = rp.rel(d.Related[i].Pos.File)
+		}
+		d.SuggestedFixes = slices.Clone(d.SuggestedFixes)
+		for i := range d.SuggestedFixes {
+			d.SuggestedFixes[i].Pos.File = rp.rel(d.SuggestedFixes[i].Pos.File)
+			d.SuggestedFixes[i].End.File = rp.rel(d.SuggestedFixes[i].End.File)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func (rp reportPaths) rel(p string) string {
+	if p == "" || links().Root != "" {
+		return relPath(p)
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return relPath(p)
+	}
+	if _, err = os.Stat(abs); err != nil {
+		return relPath(p)
+	}
+
+	root := rp.root(filepath.Dir(abs))
+	if root == "" {
+		return relPath(p)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return relPath(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// root returns the repository root containing dir, or an empty string.
+func (rp reportPaths) root(dir string) string {
+	if root, found := rp[dir]; found {
+		return root
 	}
 
+	var root string
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = rp.root(parent)
+	}
+	rp[dir] = root
+	return root
+}
+
+// ReadReport reads a JSON Report.
+func ReadReport(r io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func writeJSON(w io.Writer, v any) error {
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
 	enc.SetEscapeHTML(false)
-	return enc.Encode(r)
+	return enc.Encode(v)
 }
 
 func Text(diags []analysis.Diagnostic, w io.Writer) error {
@@ -94,68 +200,193 @@ func text(diags []analysis.Diagnostic, w io.Writer, wantColor bool) error {
 }
 
 func Markdown(diags []analysis.Diagnostic, w io.Writer, analyzers []*analysis.Analyzer, version string) error {
-	// These are set by GitHub Actions.
-	repo := os.Getenv("GITHUB_REPOSITORY")
-	if repo == "" {
-		repo = "elastic/integrations"
-	}
-	commit := os.Getenv("GITHUB_SHA")
-	if commit == "" {
-		commit = "main"
-	}
-	workspace := os.Getenv("GITHUB_WORKSPACE")
+	return writeMarkdown(w, []markdownGroup{{Diags: diags}}, analyzers, version)
+}
 
-	relPath := func(p string) string {
-		if workspace != "" {
-			p, _ = filepath.Rel(workspace, p)
-		}
-		return filepath.ToSlash(p)
+// MarkdownByOwner writes diagnostics in Markdown format with a section for
+// each code owner. A diagnostic with multiple owners is listed in the section
+// of each owner. Diagnostics without an owner are listed last.
+func MarkdownByOwner(diags []analysis.Diagnostic, w io.Writer, analyzers []*analysis.Analyzer, version string) error {
+	var groups []markdownGroup
+	for _, g := range groupByOwner(diags) {
+		groups = append(groups, markdownGroup{
+			Heading: fmt.Sprintf("# %s\n\n", escapeMarkdown(g.Name)),
+			Diags:   g.Diags,
+		})
 	}
+	return writeMarkdown(w, groups, analyzers, version)
+}
 
-	toURL := func(p analysis.Pos) string {
-		return fmt.Sprintf("https://github.com/%s/blob/%s/%s#L%d", repo, commit, relPath(p.File), p.Line)
-	}
+// markdownGroup is a section of a Markdown document.
+type markdownGroup struct {
+	Heading string // Optional heading written before the diagnostics.
+	Diags   []analysis.Diagnostic
+}
 
-	// Sort diagnostics by category, file, and line.
-	slices.SortFunc(diags, func(a, b analysis.Diagnostic) int {
-		if c := cmp.Compare(a.Category, b.Category); c != 0 {
-			return c
-		}
-		if c := cmp.Compare(a.Pos.File, b.Pos.File); c != 0 {
-			return c
-		}
-		return cmp.Compare(a.Pos.Line, b.Pos.Line)
-	})
+func writeMarkdown(w io.Writer, groups []markdownGroup, analyzers []*analysis.Analyzer, version string) error {
+	footer := fmt.Sprintf("--------\nGenerated by [fydler](https://github.com/andrewkroh/fydler) %s\n", version)
 
-	var category string
-	for _, d := range diags {
-		if category != d.Category {
-			category = d.Category
-			fmt.Fprintf(w, "## %s\n\n", d.Category)
-			for _, a := range analyzers {
-				if a.Name == d.Category {
-					fmt.Fprintf(w, "%s\n\n", a.Description)
-					if a.CanFix {
-						fmt.Fprintf(w, "_This finding is automatically fixable via the `-fix` flag._\n\n")
+	// Keep the document within GitHub's comment size limit. Space is
+	// reserved for the footer and the summary of omitted findings.
+	budget := markdownCommentLimit - utf8.RuneCountInString(footer) - markdownOmittedReserve
+
+	var (
+		b       strings.Builder
+		omitted []SummaryCount
+	)
+	for _, g := range groups {
+		// Sort diagnostics by category, file, and line. Sort a copy because
+		// the same diagnostics may be passed to other printers.
+		diags := slices.Clone(g.Diags)
+		slices.SortFunc(diags, compareDiagnostic)
+
+		var category string
+		for i, d := range diags {
+			var entry strings.Builder
+			if i == 0 {
+				entry.WriteString(g.Heading)
+			}
+			if category != d.Category {
+				category = d.Category
+				fmt.Fprintf(&entry, "## %s\n\n", d.Category)
+				for _, a := range analyzers {
+					if a.Name == d.Category {
+						fmt.Fprintf(&entry, "%s\n\n", a.Description)
+						if a.CanFix {
+							fmt.Fprintf(&entry, "_This finding is automatically fixable via the `-fix` flag._\n\n")
+						}
+						break
 					}
-					break
 				}
 			}
+
+			fmt.Fprintf(&entry, "- %s %s\n", markdownRange(d.Pos, d.End), escapeMarkdown(d.Message))
+
+			for _, r := range d.Related {
+				fmt.Fprintf(&entry, "  - %s %s\n", markdownLocation(r.Pos), escapeMarkdown(r.Message))
+			}
+			for _, fix := range d.SuggestedFixes {
+				writeMarkdownSuggestion(&entry, fix)
+			}
+			entry.WriteString("\n")
+
+			// Once one finding is omitted, omit all that follow so that the
+			// output does not have gaps.
+			n := utf8.RuneCountInString(entry.String())
+			if len(omitted) > 0 || n > budget {
+				idx := slices.IndexFunc(omitted, func(c SummaryCount) bool { return c.Name == d.Category })
+				if idx < 0 {
+					omitted = append(omitted, SummaryCount{Name: d.Category})
+					idx = len(omitted) - 1
+				}
+				omitted[idx].Count++
+				continue
+			}
+			budget -= n
+			b.WriteString(entry.String())
 		}
+	}
 
-		rel := relPath(d.Pos.File)
-		fmt.Fprintf(w, "- [%s:%d](%s) %s\n", rel, d.Pos.Line, toURL(d.Pos), escapeMarkdown(d.Message))
+	if len(omitted) > 0 {
+		var total int
+		for _, c := range omitted {
+			total += c.Count
+		}
+		fmt.Fprintf(&b, "_%s omitted to stay within the %d character limit:_\n\n",
+			pluralize(total, "finding was", "findings were"), markdownCommentLimit)
+		for _, c := range omitted {
+			fmt.Fprintf(&b, "- %s: %d\n", c.Name, c.Count)
+		}
+		b.WriteString("\n")
+	}
 
-		for _, r := range d.Related {
-			fmt.Fprintf(w, "  - [%s:%d](%s) %s\n", relPath(r.Pos.File), r.Pos.Line, toURL(r.Pos), escapeMarkdown(r.Message))
+	b.WriteString(footer)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+const (
+	// markdownCommentLimit is the maximum number of characters in a GitHub
+	// issue or pull request comment.
+	markdownCommentLimit = 65536
+
+	// markdownOmittedReserve is the space reserved for the summary of
+	// findings that were omitted due to markdownCommentLimit.
+	markdownOmittedReserve = 2048
+)
+
+// writeMarkdownSuggestion writes a suggested fix as a GitHub suggestion block
+// nested under the diagnostic's list item.
+func writeMarkdownSuggestion(w io.Writer, fix analysis.SuggestedFix) {
+	lines := fmt.Sprintf("line %d", fix.Pos.Line)
+	if fix.End.Line > fix.Pos.Line {
+		lines = fmt.Sprintf("lines %d-%d", fix.Pos.Line, fix.End.Line)
+	}
+	fmt.Fprintf(w, "\n  %s (replaces %s):\n\n", escapeMarkdown(fix.Message), lines)
+	fmt.Fprintln(w, "  ```suggestion")
+	if fix.NewText != "" {
+		for _, line := range strings.Split(fix.NewText, "\n") {
+			if line == "" {
+				fmt.Fprintln(w)
+				continue
+			}
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+	fmt.Fprintln(w, "  ```")
+}
+
+// packageName returns the name of the package containing the file. It is
+// derived from the path by locating the package's data_stream directory,
+// its elasticsearch/transform directory, or its top-level fields directory.
+// It returns an empty string when the path does not look like it belongs to
+// a package.
+func packageName(p string) string {
+	parts := strings.Split(filepath.ToSlash(filepath.Dir(p)), "/")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] == "data_stream" {
+			return parts[i-1]
 		}
-		fmt.Println()
 	}
+	for i := 1; i < len(parts)-1; i++ {
+		if parts[i] == "elasticsearch" && parts[i+1] == "transform" {
+			return parts[i-1]
+		}
+	}
+	if n := len(parts); n > 1 && parts[n-1] == "fields" {
+		return parts[n-2]
+	}
+	return ""
+}
 
-	fmt.Println("--------")
-	fmt.Printf("Generated by [fydler](https://github.com/andrewkroh/fydler) %s\n", version)
+// quotedPattern matches the double-quoted strings in a message. Analyzers
+// quote the offending attribute names and values (e.g. with %q).
+var quotedPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
 
-	return nil
+// Fingerprint returns a stable identifier for a diagnostic. It is derived from
+// the category, the field name, the relative file path, and the quoted
+// strings of the message (e.g. the name of an unknown attribute) that tell
+// apart different findings about the same field. Diagnostics that do not name
+// a field use the message instead. Line numbers and the rest of the message
+// text are intentionally excluded so that the fingerprint does not change
+// when unrelated lines are added to a file or when a message is reworded.
+func Fingerprint(d analysis.Diagnostic) string {
+	key := d.Field
+	if key == "" {
+		key = d.Message
+	}
+	quoted := quotedPattern.FindAllString(d.Message, -1)
+	return hashStrings(append([]string{d.Category, key, relPath(d.Pos.File)}, quoted...)...)
+}
+
+// hashStrings returns the hex encoded SHA-256 hash of the strings.
+func hashStrings(values ...string) string {
+	h := sha256.New()
+	for _, s := range values {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // githubMarkdownEscapes is a replacer for characters that have special meaning
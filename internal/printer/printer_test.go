@@ -0,0 +1,618 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+var testDiags = []analysis.Diagnostic{
+	{
+		Pos:      analysis.Pos{File: "packages/foo/data_stream/bar/fields/fields.yml", Line: 2, Col: 3},
+		Category: "conflict",
+		Field:    "number",
+		Message:  "number has multiple data types (long, short)",
+		Related: []analysis.RelatedInformation{
+			{Pos: analysis.Pos{File: "packages/foo/data_stream/bar/fields/fields.yml", Line: 2, Col: 3}, Message: "long"},
+			{Pos: analysis.Pos{File: "packages/foo/data_stream/baz/fields/fields.yml", Line: 4, Col: 3}, Message: "short"},
+		},
+	},
+	{
+		Pos:      analysis.Pos{File: "packages/foo/data_stream/baz/fields/fields.yml", Line: 8, Col: 3},
+		Category: "unknownattribute",
+		Field:    "message",
+		Message:  `message contains an unknown attribute "typo"`,
+	},
+	{
+		Pos:      analysis.Pos{File: "packages/foo/data_stream/bar/fields/fields.yml", Line: 10, Col: 3},
+		Category: "missingtype",
+		Field:    "pontificate",
+		Message:  "pontificate is missing a 'type'",
+	},
+}
+
+func TestMarkdownSuggestion(t *testing.T) {
+	diags := []analysis.Diagnostic{
+		{
+			Pos:      analysis.Pos{File: "fields.yml", Line: 1, Col: 3},
+			Category: "fieldgroup",
+			Message:  "foo contains 'fields' and must be declared as 'type: group'",
+			SuggestedFixes: []analysis.SuggestedFix{
+				{
+					Message: "Use 'type: group'",
+					Pos:     analysis.Pos{File: "fields.yml", Line: 2},
+					End:     analysis.Pos{File: "fields.yml", Line: 2},
+					NewText: "  type: group",
+				},
+			},
+		},
+	}
+
+	SetLinkBuilder(&LinkBuilder{})
+	t.Cleanup(func() { SetLinkBuilder(nil) })
+
+	var buf bytes.Buffer
+	require.NoError(t, Markdown(diags, &buf, nil, "v1"))
+
+	const expected = "## fieldgroup\n\n" +
+		"- fields.yml:1 foo contains &#39;fields&#39; and must be declared as &#39;type: group&#39;\n" +
+		"\n" +
+		"  Use &#39;type: group&#39; (replaces line 2):\n" +
+		"\n" +
+		"  ```suggestion\n" +
+		"    type: group\n" +
+		"  ```\n" +
+		"\n" +
+		"--------\nGenerated by [fydler](https://github.com/andrewkroh/fydler) v1\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestMarkdownLimit(t *testing.T) {
+	var diags []analysis.Diagnostic
+	for i := 0; i < 2000; i++ {
+		diags = append(diags, analysis.Diagnostic{
+			Pos:      analysis.Pos{File: "packages/foo/data_stream/bar/fields/fields.yml", Line: i + 1},
+			Category: "missingtype",
+			Message:  strings.Repeat("x", 100),
+		})
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Markdown(diags, &buf, nil, "v1"))
+
+	out := buf.String()
+	assert.LessOrEqual(t, len([]rune(out)), markdownCommentLimit)
+	assert.Contains(t, out, "findings were omitted to stay within the 65536 character limit")
+	assert.Contains(t, out, "- missingtype: ")
+	assert.True(t, strings.HasSuffix(out, "Generated by [fydler](https://github.com/andrewkroh/fydler) v1\n"))
+}
+
+func TestCheckstyle(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Checkstyle(testDiags, &buf))
+
+	const expected = `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="packages/foo/data_stream/bar/fields/fields.yml">
+    <error line="2" column="3" severity="warning" message="number has multiple data types (long, short)&#xA;packages/foo/data_stream/bar/fields/fields.yml:2 long&#xA;packages/foo/data_stream/baz/fields/fields.yml:4 short" source="fydler.conflict"></error>
+    <error line="10" column="3" severity="warning" message="pontificate is missing a &#39;type&#39;" source="fydler.missingtype"></error>
+  </file>
+  <file name="packages/foo/data_stream/baz/fields/fields.yml">
+    <error line="8" column="3" severity="warning" message="message contains an unknown attribute &#34;typo&#34;" source="fydler.unknownattribute"></error>
+  </file>
+</checkstyle>
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestGitLabCodeQuality(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, GitLabCodeQuality(testDiags, &buf))
+
+	var issues []codeQualityIssue
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &issues))
+	require.Len(t, issues, len(testDiags))
+
+	assert.Equal(t, "conflict", issues[0].CheckName)
+	assert.Equal(t, "packages/foo/data_stream/bar/fields/fields.yml", issues[0].Location.Path)
+	assert.Equal(t, 2, issues[0].Location.Lines.Begin)
+
+	// Fingerprints are unique and do not depend on the line number.
+	assert.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+	moved := testDiags[0]
+	moved.Pos.Line += 10
+	assert.Equal(t, issues[0].Fingerprint, Fingerprint(moved))
+
+	// Rewording the message does not change the fingerprint.
+	reworded := testDiags[0]
+	reworded.Message = "number has conflicting types"
+	assert.Equal(t, issues[0].Fingerprint, Fingerprint(reworded))
+
+	// Different findings about the same field have different fingerprints.
+	other := testDiags[1]
+	other.Message = `message contains an unknown attribute "tpye"`
+	assert.NotEqual(t, Fingerprint(testDiags[1]), Fingerprint(other))
+
+	// Repeated diagnostics for the same field still get unique fingerprints.
+	buf.Reset()
+	require.NoError(t, GitLabCodeQuality([]analysis.Diagnostic{testDiags[0], moved}, &buf))
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &issues))
+	require.Len(t, issues, 2)
+	assert.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+
+	// The fingerprints of repeated diagnostics do not depend on their order.
+	repeated := testDiags[0]
+	repeated.Message = "number is declared twice"
+	fingerprints := func(diags ...analysis.Diagnostic) map[string]string {
+		buf.Reset()
+		require.NoError(t, GitLabCodeQuality(diags, &buf))
+		var issues []codeQualityIssue
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &issues))
+		m := map[string]string{}
+		for _, issue := range issues {
+			m[issue.Description] = issue.Fingerprint
+		}
+		return m
+	}
+	assert.Equal(t, fingerprints(testDiags[0], repeated), fingerprints(repeated, testDiags[0]))
+
+	// An empty report is an empty array rather than null.
+	buf.Reset()
+	require.NoError(t, GitLabCodeQuality(nil, &buf))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestHTML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fields.yml")
+	require.NoError(t, os.WriteFile(path, []byte("- name: a\n  type: keyword\n- name: a\n  type: long\n"), 0o644))
+
+	diags := []analysis.Diagnostic{
+		{
+			Pos:      analysis.Pos{File: path, Line: 1, Col: 3},
+			Category: "duplicate",
+			Message:  "a is declared 2 times",
+			Related: []analysis.RelatedInformation{
+				{Pos: analysis.Pos{File: path, Line: 3, Col: 3}, Message: "additional definition"},
+			},
+		},
+	}
+	analyzers := []*analysis.Analyzer{
+		{Name: "duplicate", Description: "Detect duplicate field declarations within a directory."},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, HTML(diags, &buf, analyzers, "v1.2.3"))
+
+	out := buf.String()
+	assert.Contains(t, out, "Detect duplicate field declarations within a directory.")
+	assert.Contains(t, out, `<td class="num">1</td>`)
+	assert.Contains(t, out, "<summary>a is declared 2 times</summary>")
+	assert.Contains(t, out, `<span class="target"><span class="lineno">   3 | </span>- name: a</span>`)
+	assert.Contains(t, out, "fydler</a> v1.2.3")
+	assert.NotContains(t, out, "<script src=")
+	assert.NotContains(t, out, "<link ")
+}
+
+func TestPackageName(t *testing.T) {
+	testCases := map[string]string{
+		"packages/foo/data_stream/bar/fields/fields.yml":                         "foo",
+		"packages/foo/data_stream/bar/elasticsearch/ingest_pipeline/default.yml": "foo",
+		"packages/elasticsearch/data_stream/bar/fields/fields.yml":               "elasticsearch",
+		"packages/foo/elasticsearch/transform/latest/fields/fields.yml":          "foo",
+		"packages/foo/fields/input.yml":                                          "foo",
+		"fields.yml":                                                             "",
+	}
+
+	for path, expected := range testCases {
+		assert.Equal(t, expected, packageName(path), path)
+	}
+}
+
+func TestPretty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fields.yml")
+	require.NoError(t, os.WriteFile(path, []byte("- name: a\n  type: keyword\n- name: a\n  external: ecs\n\n- name: b\n"), 0o644))
+
+	diags := []analysis.Diagnostic{
+		{
+			Pos:      analysis.Pos{File: path, Line: 1, Col: 3},
+			Category: "duplicate",
+			Message:  "a is declared 2 times",
+			Related: []analysis.RelatedInformation{
+				{Pos: analysis.Pos{File: path, Line: 3, Col: 3}, Message: "additional definition"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Pretty(diags, &buf))
+
+	expected := strings.ReplaceAll(`duplicate: a is declared 2 times
+ --> PATH:1:3
+  |
+1 | - name: a
+  |   ^ a (keyword)
+2 |   type: keyword
+  |
+note: additional definition
+ --> PATH:3:3
+  |
+3 | - name: a
+  |   ^ a (external: ecs)
+4 |   external: ecs
+  |
+
+`, "PATH", filepath.ToSlash(path))
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestPrettyRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fields.yml")
+	require.NoError(t, os.WriteFile(path, []byte("- name: a\n  type: keyword\n  typo: true\n"), 0o644))
+
+	diags := []analysis.Diagnostic{
+		{
+			Pos:      analysis.Pos{File: path, Line: 3, Col: 3},
+			End:      analysis.Pos{File: path, Line: 3, Col: 13},
+			Category: "unknownattribute",
+			Message:  `a contains an unknown attribute "typo"`,
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Pretty(diags, &buf))
+
+	expected := strings.ReplaceAll(`unknownattribute: a contains an unknown attribute "typo"
+ --> PATH:3:3
+  |
+3 |   typo: true
+  |   ^^^^^^^^^^ a (keyword)
+  |
+
+`, "PATH", filepath.ToSlash(path))
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestPrettyColor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fields.yml")
+	require.NoError(t, os.WriteFile(path, []byte("- name: a\n"), 0o644))
+	diags := []analysis.Diagnostic{
+		{Pos: analysis.Pos{File: path, Line: 1, Col: 3}, Category: "test", Message: "message"},
+	}
+
+	// The choice of color is explicit and does not depend on the process.
+	noColor := color.NoColor
+	t.Cleanup(func() { color.NoColor = noColor })
+	for _, noColor := range []bool{true, false} {
+		color.NoColor = noColor
+
+		var buf bytes.Buffer
+		require.NoError(t, Pretty(diags, &buf))
+		assert.NotContains(t, buf.String(), "\x1b[", noColor)
+
+		buf.Reset()
+		require.NoError(t, ColorPretty(diags, &buf))
+		assert.Contains(t, buf.String(), "\x1b[", noColor)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	fix := []analysis.SuggestedFix{{Message: "Remove \"typo\""}}
+	diags := append(slices.Clone(testDiags), analysis.Diagnostic{
+		Pos:            analysis.Pos{File: "packages/other/data_stream/bar/fields/fields.yml", Line: 1, Col: 3},
+		Category:       "unknownattribute",
+		Field:          "message",
+		Message:        `message contains an unknown attribute "title"`,
+		SuggestedFixes: fix,
+	})
+	// Only diagnostics with a suggested fix are fixable.
+	diags[1].SuggestedFixes = fix
+
+	s := Summarize(diags, 1)
+
+	assert.Equal(t, 4, s.Total)
+	assert.Equal(t, 2, s.Fixable)
+	assert.Equal(t, []SummaryCount{
+		{Name: "unknownattribute", Count: 2, Fixable: 2},
+		{Name: "conflict", Count: 1},
+		{Name: "missingtype", Count: 1},
+	}, s.Analyzers)
+	assert.Equal(t, []PackageSummary{
+		{
+			SummaryCount: SummaryCount{Name: "foo", Count: 3, Fixable: 1},
+			Analyzers: []SummaryCount{
+				{Name: "conflict", Count: 1},
+				{Name: "missingtype", Count: 1},
+				{Name: "unknownattribute", Count: 1, Fixable: 1},
+			},
+		},
+		{
+			SummaryCount: SummaryCount{Name: "other", Count: 1, Fixable: 1},
+			Analyzers: []SummaryCount{
+				{Name: "unknownattribute", Count: 1, Fixable: 1},
+			},
+		},
+	}, s.Packages)
+	assert.Equal(t, []SummaryCount{{Name: "foo", Count: 3, Fixable: 1}}, s.TopPackages)
+	assert.Nil(t, s.Owners)
+}
+
+func TestSummarizeOwners(t *testing.T) {
+	diags := slices.Clone(testDiags)
+	diags[0].Owners = []string{"@elastic/foo", "@elastic/bar"}
+	diags[1].Owners = []string{"@elastic/foo"}
+
+	s := Summarize(diags, 10)
+
+	assert.Equal(t, []OwnerSummary{
+		{
+			SummaryCount: SummaryCount{Name: "@elastic/bar", Count: 1},
+			Packages:     []SummaryCount{{Name: "foo", Count: 1}},
+		},
+		{
+			SummaryCount: SummaryCount{Name: "@elastic/foo", Count: 2},
+			Packages:     []SummaryCount{{Name: "foo", Count: 2}},
+		},
+		{
+			SummaryCount: SummaryCount{Name: unowned, Count: 1},
+			Packages:     []SummaryCount{{Name: "foo", Count: 1}},
+		},
+	}, s.Owners)
+}
+
+func TestMarkdownByOwner(t *testing.T) {
+	SetLinkBuilder(&LinkBuilder{})
+	t.Cleanup(func() { SetLinkBuilder(nil) })
+
+	diags := slices.Clone(testDiags)
+	diags[1].Owners = []string{"@elastic/foo"}
+
+	var buf bytes.Buffer
+	require.NoError(t, MarkdownByOwner(diags, &buf, nil, "v1"))
+
+	out := buf.String()
+	foo := strings.Index(out, "# <span>@</span>elastic/foo\n")
+	none := strings.Index(out, "# (unowned)\n")
+	require.True(t, foo >= 0 && none > foo, out)
+	assert.Contains(t, out[foo:none], "unknown attribute")
+	assert.Contains(t, out[none:], "multiple data types")
+	assert.Contains(t, out[none:], "missing a &#39;type&#39;")
+}
+
+func TestDiffReports(t *testing.T) {
+	oldReport := &Report{Diags: testDiags}
+
+	moved := testDiags[0]
+	moved.Pos.Line += 5
+	added := analysis.Diagnostic{
+		Pos:      analysis.Pos{File: "packages/foo/data_stream/bar/fields/fields.yml", Line: 20, Col: 3},
+		Category: "missingtype",
+		Message:  "bogus is missing a 'type'",
+	}
+	newReport := &Report{Diags: []analysis.Diagnostic{moved, testDiags[2], added}}
+
+	diff := DiffReports(oldReport, newReport)
+	assert.Equal(t, []analysis.Diagnostic{added}, diff.Added)
+	assert.Equal(t, []analysis.Diagnostic{testDiags[1]}, diff.Removed)
+	assert.Equal(t, []analysis.Diagnostic{moved, testDiags[2]}, diff.Unchanged)
+	assert.Equal(t, []ReportDiffCount{
+		{Name: "conflict", Unchanged: 1},
+		{Name: "missingtype", Added: 1, Unchanged: 1},
+		{Name: "unknownattribute", Removed: 1},
+	}, diff.Analyzers)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	analyzers := []*analysis.Analyzer{{Name: "conflict", Description: "Detect conflicts."}}
+
+	var buf bytes.Buffer
+	require.NoError(t, JSON(testDiags, &buf, analyzers, "v1.2.3\n", []string{"-set-output", "json"}))
+
+	r, err := ReadReport(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, testDiags, r.Diags)
+	assert.Equal(t, "v1.2.3", r.Version)
+	assert.Equal(t, []string{"-set-output", "json"}, r.Args)
+	assert.Equal(t, []ReportAnalyzer{{Name: "conflict", Description: "Detect conflicts."}}, r.Analyzers)
+}
+
+func TestJSONRepoRelativePaths(t *testing.T) {
+	t.Setenv("GITHUB_WORKSPACE", "")
+	t.Setenv("FYDLER_LINK_ROOT", "")
+
+	// The same file in two checkouts results in the same report.
+	var reports []*Report
+	for _, checkout := range []string{"a", "b"} {
+		root := filepath.Join(t.TempDir(), checkout)
+		path := filepath.Join(root, "packages", "foo", "data_stream", "bar", "fields", "fields.yml")
+		require.NoError(t, os.MkdirAll(filepath.Join(root, ".git"), 0o755))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+
+		d := testDiags[0]
+		d.Pos.File = path
+		var buf bytes.Buffer
+		require.NoError(t, JSON([]analysis.Diagnostic{d}, &buf, nil, "", nil))
+
+		r, err := ReadReport(&buf)
+		require.NoError(t, err)
+		require.Len(t, r.Diags, 1)
+		assert.Equal(t, "packages/foo/data_stream/bar/fields/fields.yml", r.Diags[0].Pos.File)
+		reports = append(reports, r)
+	}
+
+	diff := DiffReports(reports[0], reports[1])
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Len(t, diff.Unchanged, 1)
+}
+
+func TestTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(`
+{{- range groupByPackage .Diags}}{{.Name}}: {{len .Diags}}
+{{end}}
+{{- range groupByCategory .Diags}}{{.Name}}{{with analyzer .Name}} fix={{.CanFix}}{{end}}
+{{end}}
+{{- range .Diags}}{{csv (relPath .Pos.File) .Pos.Line .Message}}{{end}}
+{{- sourceURL (index .Diags 0).Pos}}`), 0o644))
+
+	t.Setenv("GITHUB_REPOSITORY", "")
+	t.Setenv("GITHUB_SHA", "")
+	analyzers := []*analysis.Analyzer{{Name: "unknownattribute", CanFix: true}}
+
+	var buf bytes.Buffer
+	require.NoError(t, Template(testDiags, &buf, analyzers, "v1", path))
+
+	const expected = `foo: 3
+conflict
+missingtype
+unknownattribute fix=true
+packages/foo/data_stream/bar/fields/fields.yml,2,"number has multiple data types (long, short)"
+packages/foo/data_stream/bar/fields/fields.yml,10,pontificate is missing a 'type'
+packages/foo/data_stream/baz/fields/fields.yml,8,"message contains an unknown attribute ""typo"""
+https://github.com/elastic/integrations/blob/main/packages/foo/data_stream/bar/fields/fields.yml#L2`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestLinkBuilder(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		Builder LinkBuilder
+		Line    int
+		EndLine int
+		URL     string
+	}{
+		{
+			Name:    "github",
+			Builder: LinkBuilder{Provider: "github", Repo: "elastic/integrations", Ref: "main", Root: "/src"},
+			Line:    4,
+			URL:     "https://github.com/elastic/integrations/blob/main/packages/foo/fields.yml#L4",
+		},
+		{
+			Name:    "github_range",
+			Builder: LinkBuilder{Provider: "github", Repo: "elastic/integrations", Ref: "main", Root: "/src"},
+			Line:    4,
+			EndLine: 6,
+			URL:     "https://github.com/elastic/integrations/blob/main/packages/foo/fields.yml#L4-L6",
+		},
+		{
+			Name:    "ghes",
+			Builder: LinkBuilder{Provider: "ghes", BaseURL: "https://github.example.com/", Repo: "org/integrations", Ref: "abc123", Root: "/src"},
+			Line:    4,
+			URL:     "https://github.example.com/org/integrations/blob/abc123/packages/foo/fields.yml#L4",
+		},
+		{
+			Name:    "gitlab_range",
+			Builder: LinkBuilder{Provider: "gitlab", Repo: "group/integrations", Ref: "main", Root: "/src"},
+			Line:    4,
+			EndLine: 6,
+			URL:     "https://gitlab.com/group/integrations/-/blob/main/packages/foo/fields.yml#L4-6",
+		},
+		{
+			Name:    "bitbucket_range",
+			Builder: LinkBuilder{Provider: "bitbucket", Repo: "team/integrations", Ref: "main", Root: "/src"},
+			Line:    4,
+			EndLine: 6,
+			URL:     "https://bitbucket.org/team/integrations/src/main/packages/foo/fields.yml#lines-4:6",
+		},
+		{
+			Name:    "custom_template",
+			Builder: LinkBuilder{Template: "https://code.example.com/{repo}/{path}?ref={ref}&from={line}&to={end_line}", Repo: "r", Ref: "dev", Root: "/src"},
+			Line:    4,
+			URL:     "https://code.example.com/r/packages/foo/fields.yml?ref=dev&from=4&to=4",
+		},
+		{
+			Name:    "no_repo",
+			Builder: LinkBuilder{Provider: "gitlab", Ref: "main"},
+			Line:    4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.URL, tc.Builder.URL("/src/packages/foo/fields.yml", tc.Line, tc.EndLine))
+		})
+	}
+}
+
+func TestNewLinkBuilderGitHubEnterprise(t *testing.T) {
+	t.Setenv("GITHUB_SERVER_URL", "https://github.example.com")
+	t.Setenv("GITHUB_REPOSITORY", "org/integrations")
+	t.Setenv("GITHUB_SHA", "abc123")
+	t.Setenv("GITHUB_WORKSPACE", "/work")
+
+	expected := &LinkBuilder{
+		Provider: "ghes",
+		BaseURL:  "https://github.example.com",
+		Repo:     "org/integrations",
+		Ref:      "abc123",
+		Root:     "/work",
+	}
+	assert.Equal(t, expected, NewLinkBuilder(LinkBuilder{}))
+	assert.Equal(t, expected, NewLinkBuilder(LinkBuilder{Provider: "ghes"}))
+}
+
+func TestNewLinkBuilder(t *testing.T) {
+	t.Setenv("GITLAB_CI", "true")
+	t.Setenv("CI_SERVER_URL", "https://gitlab.example.com")
+	t.Setenv("CI_PROJECT_PATH", "group/integrations")
+	t.Setenv("CI_COMMIT_SHA", "abc123")
+	t.Setenv("CI_PROJECT_DIR", "/builds/group/integrations")
+	t.Setenv("FYDLER_LINK_REF", "def456")
+
+	b := NewLinkBuilder(LinkBuilder{Root: "/src"})
+	assert.Equal(t, &LinkBuilder{
+		Provider: "gitlab",
+		BaseURL:  "https://gitlab.example.com",
+		Repo:     "group/integrations",
+		Ref:      "def456",
+		Root:     "/src",
+	}, b)
+	assert.NoError(t, b.Validate())
+
+	// Values from the CI environment are not used for a different provider.
+	b = NewLinkBuilder(LinkBuilder{Provider: "github"})
+	assert.Equal(t, &LinkBuilder{
+		Provider: "github",
+		Repo:     "elastic/integrations",
+		Ref:      "def456",
+	}, b)
+
+	t.Setenv("FYDLER_LINK_PROVIDER", "bitbucket")
+	t.Setenv("FYDLER_LINK_REPO", "team/integrations")
+	assert.Equal(t, &LinkBuilder{
+		Provider: "bitbucket",
+		Repo:     "team/integrations",
+		Ref:      "def456",
+	}, NewLinkBuilder(LinkBuilder{}))
+
+	assert.Error(t, (&LinkBuilder{Provider: "ghes", Repo: "a/b"}).Validate())
+	assert.Error(t, (&LinkBuilder{Provider: "svn", Repo: "a/b"}).Validate())
+}
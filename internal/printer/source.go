@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"os"
+	"strings"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+// sourceLine is a single line from a source file.
+type sourceLine struct {
+	Number int
+	Text   string
+	Target bool // Target is true for the line referenced by the position.
+}
+
+// sourceCache reads source files on demand and caches their lines.
+type sourceCache map[string][]string
+
+func (c sourceCache) lines(path string) []string {
+	lines, found := c[path]
+	if !found {
+		if data, err := os.ReadFile(path); err == nil {
+			s := strings.ReplaceAll(string(data), "\r\n", "\n")
+			lines = strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+		}
+		c[path] = lines
+	}
+	return lines
+}
+
+// snippet returns the lines surrounding the position with up to context
+// lines before and after it. It returns nil if the file cannot be read or
+// the position does not refer to a line in the file.
+func (c sourceCache) snippet(p analysis.Pos, context int) []sourceLine {
+	lines := c.lines(p.File)
+	if p.Line < 1 || p.Line > len(lines) {
+		return nil
+	}
+
+	first := max(p.Line-context, 1)
+	last := min(p.Line+context, len(lines))
+	out := make([]sourceLine, 0, last-first+1)
+	for n := first; n <= last; n++ {
+		out = append(out, sourceLine{
+			Number: n,
+			Text:   lines[n-1],
+			Target: n == p.Line,
+		})
+	}
+	return out
+}
@@ -0,0 +1,230 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+// unknownPackage is the name used for diagnostics whose path does not belong
+// to a package.
+const unknownPackage = "(unknown)"
+
+// SummaryCount is the number of diagnostics in a group.
+type SummaryCount struct {
+	Name    string `json:"name"`
+	Count   int    `json:"count"`
+	Fixable int    `json:"fixable"`
+}
+
+// PackageSummary is the number of diagnostics in a package, broken down by
+// analyzer.
+type PackageSummary struct {
+	SummaryCount
+	Analyzers []SummaryCount `json:"analyzers"`
+}
+
+// OwnerSummary is the number of diagnostics owned by a code owner, broken
+// down by package.
+type OwnerSummary struct {
+	SummaryCount
+	Packages []SummaryCount `json:"packages"`
+}
+
+// Summary is an aggregation of diagnostics.
+type Summary struct {
+	Total       int              `json:"total"`
+	Fixable     int              `json:"fixable"`
+	Analyzers   []SummaryCount   `json:"analyzers"`
+	Packages    []PackageSummary `json:"packages"`
+	TopPackages []SummaryCount   `json:"top_packages"`
+	Owners      []OwnerSummary   `json:"owners,omitempty"` // Only present when code owners are known.
+}
+
+// Summarize aggregates the diagnostics by analyzer, by package, and by package
+// and analyzer. A diagnostic is counted as fixable when it has a suggested
+// fix, so fixable counts are only reported when suggested fixes are enabled.
+// The topN packages with the most
+// diagnostics are listed in TopPackages. Groups are ordered by descending
+// count and then by name. When any diagnostic has code owners, the
+// diagnostics are also aggregated by owner and by owner and package.
+func Summarize(diags []analysis.Diagnostic, topN int) Summary {
+	byAnalyzer := map[string]*SummaryCount{}
+	byPackage := map[string]*PackageSummary{}
+	byPackageAnalyzer := map[[2]string]*SummaryCount{}
+	byOwner := map[string]*OwnerSummary{}
+	byOwnerPackage := map[[2]string]*SummaryCount{}
+	hasOwners := slices.ContainsFunc(diags, func(d analysis.Diagnostic) bool { return len(d.Owners) > 0 })
+
+	var s Summary
+	for _, d := range diags {
+		pkg := packageName(d.Pos.File)
+		if pkg == "" {
+			pkg = unknownPackage
+		}
+
+		fixable := 0
+		if len(d.SuggestedFixes) > 0 {
+			fixable = 1
+		}
+		s.Total++
+		s.Fixable += fixable
+
+		a := getOrCreate(byAnalyzer, d.Category, func() *SummaryCount {
+			return &SummaryCount{Name: d.Category}
+		})
+		a.Count++
+		a.Fixable += fixable
+
+		p := getOrCreate(byPackage, pkg, func() *PackageSummary {
+			return &PackageSummary{SummaryCount: SummaryCount{Name: pkg}}
+		})
+		p.Count++
+		p.Fixable += fixable
+
+		pa := getOrCreate(byPackageAnalyzer, [2]string{pkg, d.Category}, func() *SummaryCount {
+			return &SummaryCount{Name: d.Category}
+		})
+		pa.Count++
+		pa.Fixable += fixable
+
+		if !hasOwners {
+			continue
+		}
+		for _, owner := range diagnosticOwners(d) {
+			o := getOrCreate(byOwner, owner, func() *OwnerSummary {
+				return &OwnerSummary{SummaryCount: SummaryCount{Name: owner}}
+			})
+			o.Count++
+			o.Fixable += fixable
+
+			op := getOrCreate(byOwnerPackage, [2]string{owner, pkg}, func() *SummaryCount {
+				return &SummaryCount{Name: pkg}
+			})
+			op.Count++
+			op.Fixable += fixable
+		}
+	}
+
+	for key, op := range byOwnerPackage {
+		o := byOwner[key[0]]
+		o.Packages = append(o.Packages, *op)
+	}
+
+	for key, pa := range byPackageAnalyzer {
+		p := byPackage[key[0]]
+		p.Analyzers = append(p.Analyzers, *pa)
+	}
+
+	s.Analyzers = make([]SummaryCount, 0, len(byAnalyzer))
+	for _, a := range byAnalyzer {
+		s.Analyzers = append(s.Analyzers, *a)
+	}
+	slices.SortFunc(s.Analyzers, compareSummaryCount)
+
+	s.Packages = make([]PackageSummary, 0, len(byPackage))
+	for _, p := range byPackage {
+		slices.SortFunc(p.Analyzers, compareSummaryCount)
+		s.Packages = append(s.Packages, *p)
+	}
+	slices.SortFunc(s.Packages, func(a, b PackageSummary) int {
+		return compareSummaryCount(a.SummaryCount, b.SummaryCount)
+	})
+
+	s.TopPackages = make([]SummaryCount, 0, min(topN, len(s.Packages)))
+	for _, p := range s.Packages[:min(max(topN, 0), len(s.Packages))] {
+		s.TopPackages = append(s.TopPackages, p.SummaryCount)
+	}
+
+	for _, o := range byOwner {
+		slices.SortFunc(o.Packages, compareSummaryCount)
+		s.Owners = append(s.Owners, *o)
+	}
+	slices.SortFunc(s.Owners, func(a, b OwnerSummary) int {
+		return compareOwner(a.Name, b.Name)
+	})
+
+	return s
+}
+
+// SummaryText writes a summary of the diagnostics as text tables.
+func SummaryText(diags []analysis.Diagnostic, w io.Writer, topN int) error {
+	s := Summarize(diags, topN)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d diagnostics (%d fixable) in %d packages\n", s.Total, s.Fixable, len(s.Packages))
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "ANALYZER\tCOUNT\tFIXABLE")
+	writeSummaryCounts(tw, s.Analyzers, "")
+
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "TOP %d PACKAGES\tCOUNT\tFIXABLE\n", len(s.TopPackages))
+	writeSummaryCounts(tw, s.TopPackages, "")
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "PACKAGE / ANALYZER\tCOUNT\tFIXABLE")
+	for _, p := range s.Packages {
+		writeSummaryCounts(tw, []SummaryCount{p.SummaryCount}, "")
+		writeSummaryCounts(tw, p.Analyzers, "  ")
+	}
+
+	if len(s.Owners) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "OWNER / PACKAGE\tCOUNT\tFIXABLE")
+		for _, o := range s.Owners {
+			writeSummaryCounts(tw, []SummaryCount{o.SummaryCount}, "")
+			writeSummaryCounts(tw, o.Packages, "  ")
+		}
+	}
+
+	return tw.Flush()
+}
+
+func writeSummaryCounts(w io.Writer, counts []SummaryCount, indent string) {
+	for _, c := range counts {
+		fmt.Fprintf(w, "%s%s\t%d\t%d\n", indent, c.Name, c.Count, c.Fixable)
+	}
+}
+
+// SummaryJSON writes a summary of the diagnostics as JSON.
+func SummaryJSON(diags []analysis.Diagnostic, w io.Writer, topN int) error {
+	return writeJSON(w, Summarize(diags, topN))
+}
+
+func compareSummaryCount(a, b SummaryCount) int {
+	if c := cmp.Compare(b.Count, a.Count); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Name, b.Name)
+}
+
+func getOrCreate[K comparable, V any](m map[K]*V, key K, create func() *V) *V {
+	v, found := m[key]
+	if !found {
+		v = create()
+		m[key] = v
+	}
+	return v
+}
@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package printer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/andrewkroh/fydler/internal/analysis"
+)
+
+// TemplateData is the data passed to user-supplied templates.
+type TemplateData struct {
+	Diags     []analysis.Diagnostic // Diagnostics sorted by category, file, and line.
+	Analyzers []*analysis.Analyzer
+	Version   string
+}
+
+// DiagnosticGroup is a named group of diagnostics.
+type DiagnosticGroup struct {
+	Name  string
+	Diags []analysis.Diagnostic
+}
+
+// Template renders the diagnostics using the Go text/template file at
+// templatePath. In addition to the standard template functions, these
+// functions are available:
+//
+//	relPath PATH              Path relative to the repository root.
+//	sourceURL POS             URL linking to the position in the source repository (see LinkBuilder).
+//	rangeURL START END        URL linking to the lines from START through END (e.g. .Pos .End).
+//	packageName PATH          Name of the package containing the path.
+//	fingerprint DIAG          Line-insensitive identifier of the diagnostic.
+//	groupByCategory DIAGS     Diagnostics grouped by category ([]DiagnosticGroup).
+//	groupByPackage DIAGS      Diagnostics grouped by package ([]DiagnosticGroup).
+//	groupByOwner DIAGS        Diagnostics grouped by code owner ([]DiagnosticGroup).
+//	analyzer NAME             Analyzer with the given name, or nil.
+//	csv VALUE...              Values formatted as a CSV record (with newline).
+//	json VALUE                Value encoded as JSON.
+func Template(diags []analysis.Diagnostic, w io.Writer, analyzers []*analysis.Analyzer, version, templatePath string) error {
+	tmpl, err := template.New(filepath.Base(templatePath)).
+		Funcs(templateFuncs(analyzers)).
+		ParseFiles(templatePath)
+	if err != nil {
+		return err
+	}
+
+	diags = slices.Clone(diags)
+	slices.SortFunc(diags, compareDiagnostic)
+
+	return tmpl.Execute(w, TemplateData{
+		Diags:     diags,
+		Analyzers: analyzers,
+		Version:   strings.TrimSpace(version),
+	})
+}
+
+func templateFuncs(analyzers []*analysis.Analyzer) template.FuncMap {
+	return template.FuncMap{
+		"relPath":     relPath,
+		"sourceURL":   sourceURL,
+		"rangeURL":    rangeURL,
+		"packageName": packageName,
+		"fingerprint": Fingerprint,
+		"groupByCategory": func(diags []analysis.Diagnostic) []DiagnosticGroup {
+			return groupDiagnostics(diags, func(d analysis.Diagnostic) string { return d.Category })
+		},
+		"groupByPackage": func(diags []analysis.Diagnostic) []DiagnosticGroup {
+			return groupDiagnostics(diags, func(d analysis.Diagnostic) string { return packageName(d.Pos.File) })
+		},
+		"groupByOwner": groupByOwner,
+		"analyzer": func(name string) *analysis.Analyzer {
+			for _, a := range analyzers {
+				if a.Name == name {
+					return a
+				}
+			}
+			return nil
+		},
+		"csv": func(values ...any) (string, error) {
+			record := make([]string, 0, len(values))
+			for _, v := range values {
+				s, err := toString(v)
+				if err != nil {
+					return "", err
+				}
+				record = append(record, s)
+			}
+
+			var sb strings.Builder
+			cw := csv.NewWriter(&sb)
+			if err := cw.Write(record); err != nil {
+				return "", err
+			}
+			cw.Flush()
+			return sb.String(), cw.Error()
+		},
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+}
+
+// groupDiagnostics groups the diagnostics by key. Groups are sorted by name
+// and diagnostics retain their relative order.
+func groupDiagnostics(diags []analysis.Diagnostic, key func(analysis.Diagnostic) string) []DiagnosticGroup {
+	index := map[string]int{}
+	var groups []DiagnosticGroup
+	for _, d := range diags {
+		k := key(d)
+		i, found := index[k]
+		if !found {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, DiagnosticGroup{Name: k})
+		}
+		groups[i].Diags = append(groups[i].Diags, d)
+	}
+	slices.SortStableFunc(groups, func(a, b DiagnosticGroup) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return groups
+}
+
+// toString converts a template value to a string using its String method
+// when available.
+func toString(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case interface{ String() string }:
+		return v.String(), nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
@@ -21,34 +21,48 @@
 package main
 
 import (
+	"github.com/andrewkroh/fydler/internal/analysis/allowedvalues"
 	"github.com/andrewkroh/fydler/internal/analysis/conflict"
 	"github.com/andrewkroh/fydler/internal/analysis/duplicate"
 	"github.com/andrewkroh/fydler/internal/analysis/dynamicfield"
 	"github.com/andrewkroh/fydler/internal/analysis/ecsnamespace"
+	"github.com/andrewkroh/fydler/internal/analysis/ecsupgrade"
 	"github.com/andrewkroh/fydler/internal/analysis/fieldgroup"
 	"github.com/andrewkroh/fydler/internal/analysis/invalidattribute"
 	"github.com/andrewkroh/fydler/internal/analysis/isarray"
 	"github.com/andrewkroh/fydler/internal/analysis/missingtype"
 	"github.com/andrewkroh/fydler/internal/analysis/nesting"
 	"github.com/andrewkroh/fydler/internal/analysis/objectmapping"
+	"github.com/andrewkroh/fydler/internal/analysis/orphan"
+	"github.com/andrewkroh/fydler/internal/analysis/pipelinetype"
+	"github.com/andrewkroh/fydler/internal/analysis/staleecs"
+	"github.com/andrewkroh/fydler/internal/analysis/undeclared"
 	"github.com/andrewkroh/fydler/internal/analysis/unknownattribute"
 	"github.com/andrewkroh/fydler/internal/analysis/useecs"
+	"github.com/andrewkroh/fydler/internal/analysis/valuetype"
 	"github.com/andrewkroh/fydler/internal/fydler"
 )
 
 func main() {
 	fydler.Main(
+		allowedvalues.Analyzer,
 		conflict.Analyzer,
 		duplicate.Analyzer,
 		dynamicfield.Analyzer,
 		ecsnamespace.Analyzer,
+		ecsupgrade.Analyzer,
 		fieldgroup.Analyzer,
 		invalidattribute.Analyzer,
 		isarray.Analyzer,
 		missingtype.Analyzer,
 		nesting.Analyzer,
 		objectmapping.Analyzer,
+		orphan.Analyzer,
+		pipelinetype.Analyzer,
+		staleecs.Analyzer,
+		undeclared.Analyzer,
 		unknownattribute.Analyzer,
 		useecs.Analyzer,
+		valuetype.Analyzer,
 	)
 }